@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// The supported values for -json-output-case. Our struct tags and envelope keys are
+// always snake_case, and with camel they are converted on the way out and back.
+const (
+	jsonCaseSnake = "snake"
+	jsonCaseCamel = "camel"
+)
+
+// outputKey returns key as it appears in responses with the configured key case.
+func (app *application) outputKey(key string) string {
+	if app.config.jsonOutputCase == jsonCaseCamel {
+		return snakeToCamel(key)
+	}
+	return key
+}
+
+// applyOutputCase rewrites every object key in the JSON value js to the configured
+// output case, keeping the key order, and re-indents it the same way as writeJSON().
+// With snake case js is returned unchanged.
+func (app *application) applyOutputCase(js []byte) ([]byte, error) {
+	if app.config.jsonOutputCase != jsonCaseCamel {
+		return js, nil
+	}
+
+	value, err := parseOrdered(js)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err = renameJSONKeys(value, snakeToCamel)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.MarshalIndent(value, "", "\t")
+}
+
+// normalizeInputKeys rewrites the object keys of a request body, at any depth, to
+// the canonical snake_case names the strict decoder expects. Keys in aliases are
+// renamed to their canonical names. With camel case configured, any other key is
+// converted from camelCase, so that clients can send back exactly what they
+// received; snake_case keys are still accepted. If the body isn't a single valid
+// JSON value it is returned unchanged for the decoder to report on.
+func (app *application) normalizeInputKeys(body []byte, aliases map[string]string) ([]byte, error) {
+	camel := app.config.jsonOutputCase == jsonCaseCamel
+	if len(aliases) == 0 && !camel {
+		return body, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+
+	value, err := decodeOrdered(dec)
+	if err != nil {
+		return body, nil
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return body, nil
+	}
+
+	value, err = renameJSONKeys(value, func(key string) string {
+		if canonical, ok := aliases[key]; ok {
+			return canonical
+		}
+		if camel {
+			return camelToSnake(key)
+		}
+		return key
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(value)
+}
+
+// renameJSONKeys applies rename to the keys of every object within a value produced
+// by decodeOrdered(). It is an error for two keys of the same object to be renamed
+// to the same thing.
+func renameJSONKeys(value any, rename func(string) string) (any, error) {
+	switch value := value.(type) {
+	case orderedObject:
+		seen := make(map[string]string, len(value))
+		renamed := make(orderedObject, len(value))
+
+		for i, member := range value {
+			key := rename(member.key)
+			if original, clash := seen[key]; clash {
+				return nil, fmt.Errorf("body contains both %q and %q", original, member.key)
+			}
+			seen[key] = member.key
+
+			element, err := renameJSONKeys(member.value, rename)
+			if err != nil {
+				return nil, err
+			}
+			renamed[i] = orderedMember{key, element}
+		}
+		return renamed, nil
+
+	case []any:
+		renamed := make([]any, len(value))
+		for i, element := range value {
+			element, err := renameJSONKeys(element, rename)
+			if err != nil {
+				return nil, err
+			}
+			renamed[i] = element
+		}
+		return renamed, nil
+	}
+
+	return value, nil
+}
+
+// snakeToCamel converts a snake_case key such as "created_at" to "createdAt".
+func snakeToCamel(key string) string {
+	parts := strings.Split(key, "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// camelToSnake converts a camelCase key such as "createdAt" to "created_at". Keys
+// that are already snake_case are returned unchanged.
+func camelToSnake(key string) string {
+	var b strings.Builder
+	for i, r := range key {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
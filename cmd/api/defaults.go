@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// applyDefaults sets every field of the struct pointed to by dst that has a
+// `default:"..."` tag to the tag's value. readJSON() calls this *before* decoding
+// rather than after, so that anything the client sends, including an explicit zero
+// value, overwrites the default and only omitted fields keep it. A null overwrites
+// pointer and slice fields, but encoding/json ignores null for other kinds, so they
+// keep the default. Pointer fields are allocated and point at the default value.
+// Slice defaults are given as comma-separated values.
+func applyDefaults(dst any) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+
+	return applyStructDefaults(rv.Elem())
+}
+
+func applyStructDefaults(rv reflect.Value) error {
+	rt := rv.Type()
+
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		fv := rv.Field(i)
+
+		tag, ok := field.Tag.Lookup("default")
+		if !ok {
+			// Recurse into nested structs so that their fields can carry defaults too.
+			if fv.Kind() == reflect.Struct {
+				err := applyStructDefaults(fv)
+				if err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if fv.Kind() == reflect.Pointer {
+			fv.Set(reflect.New(fv.Type().Elem()))
+			fv = fv.Elem()
+		}
+
+		err := setFromString(fv, tag)
+		if err != nil {
+			return fmt.Errorf("invalid default %q for field %s: %w", tag, field.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// setFromString parses s according to the kind of v and stores the result in v.
+func setFromString(v reflect.Value, s string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		parts := []string{}
+		if s != "" {
+			parts = strings.Split(s, ",")
+		}
+
+		slice := reflect.MakeSlice(v.Type(), len(parts), len(parts))
+		for i, part := range parts {
+			err := setFromString(slice.Index(i), strings.TrimSpace(part))
+			if err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+	default:
+		return fmt.Errorf("unsupported kind %s", v.Kind())
+	}
+
+	return nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type defaultsInput struct {
+	Title    string   `json:"title" default:"untitled"`
+	Year     int32    `json:"year" default:"2000"`
+	Ratio    float64  `json:"ratio" default:"0.5"`
+	Public   bool     `json:"public" default:"true"`
+	Page     *int     `json:"page" default:"1"`
+	Genres   []string `json:"genres" default:"drama, war"`
+	Counts   []int    `json:"counts" default:""`
+	Untagged string   `json:"untagged"`
+	Nested   struct {
+		Limit uint8 `json:"limit" default:"20"`
+	} `json:"nested"`
+}
+
+func TestReadJSONDefaults(t *testing.T) {
+	one := 1
+	seven := 7
+
+	base := func() defaultsInput {
+		var want defaultsInput
+		want.Title = "untitled"
+		want.Year = 2000
+		want.Ratio = 0.5
+		want.Public = true
+		want.Page = &one
+		want.Genres = []string{"drama", "war"}
+		want.Counts = []int{}
+		want.Nested.Limit = 20
+		return want
+	}
+
+	tests := []struct {
+		name string
+		body string
+		want func(*defaultsInput)
+	}{
+		{"Omitted fields keep their defaults", `{}`, func(*defaultsInput) {}},
+		{"Explicit zero values override", `{"title":"","year":0,"ratio":0,"public":false,"genres":[],"nested":{"limit":0}}`, func(want *defaultsInput) {
+			want.Title, want.Year, want.Ratio, want.Public = "", 0, 0, false
+			want.Genres = []string{}
+			want.Nested.Limit = 0
+		}},
+		{"Values override", `{"title":"Up","page":7,"genres":["animation"]}`, func(want *defaultsInput) {
+			want.Title = "Up"
+			want.Page = &seven
+			want.Genres = []string{"animation"}
+		}},
+		{"Null overrides a pointer field", `{"page":null}`, func(want *defaultsInput) {
+			want.Page = nil
+		}},
+		{"Null leaves other fields alone", `{"title":null,"genres":null}`, func(want *defaultsInput) {
+			want.Genres = nil
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+
+			var got defaultsInput
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			err := app.readJSON(httptest.NewRecorder(), req, &got)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := base()
+			tt.want(&want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %+v; want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestApplyDefaultsPointerAllocated(t *testing.T) {
+	var dst defaultsInput
+	err := applyDefaults(&dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dst.Page == nil || *dst.Page != 1 {
+		t.Errorf("got page %v; want a pointer to 1", dst.Page)
+	}
+
+	// Each call allocates a new value, so decoded inputs never share the default.
+	var other defaultsInput
+	applyDefaults(&other)
+	if other.Page == dst.Page {
+		t.Error("got the same page pointer for two destinations")
+	}
+}
+
+func TestApplyDefaultsBadTag(t *testing.T) {
+	tests := []struct {
+		name string
+		dst  any
+	}{
+		{"Not a number", &struct {
+			Year int32 `default:"soon"`
+		}{}},
+		{"Out of range", &struct {
+			Limit uint8 `default:"300"`
+		}{}},
+		{"Not a bool", &struct {
+			Public bool `default:"yes please"`
+		}{}},
+		{"Bad slice element", &struct {
+			Counts []int `default:"1,two"`
+		}{}},
+		{"Unsupported kind", &struct {
+			Meta map[string]string `default:"a=b"`
+		}{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := applyDefaults(tt.dst); err == nil {
+				t.Error("got no error; want one")
+			}
+		})
+	}
+}
+
+func TestApplyDefaultsIgnoresNonStructs(t *testing.T) {
+	var n int
+	var m map[string]any
+	for _, dst := range []any{&n, &m, nil, defaultsInput{}} {
+		if err := applyDefaults(dst); err != nil {
+			t.Errorf("got error %s for %T", err, dst)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// addDeprecationWarnings adds a "Warning: 299" header to the response for each
+// configured deprecated field that is present in the encoded JSON body js. Fields
+// are dot-separated paths into the envelope, such as "movie.year". Arrays along the
+// path are searched element by element, so "movies.year" matches a list of movies.
+// In a JSON:API document the same paths are looked for under the resource objects,
+// so "movie.year" matches "data.attributes.year". This is purely advisory:
+// deprecated fields are still sent as normal. Any headers for the response must
+// already be set, since the Content-Type decides which form of the path is used.
+func (app *application) addDeprecationWarnings(w http.ResponseWriter, js []byte) {
+	jsonAPI := w.Header().Get("Content-Type") == jsonAPIMediaType
+
+	for _, field := range app.config.deprecatedFields {
+		// Match and report the field as the client sees it, in the output key case.
+		path := strings.Split(field, ".")
+		for i := range path {
+			path[i] = app.outputKey(path[i])
+		}
+		if jsonAPI {
+			path = jsonAPIPath(path)
+		}
+		field = strings.Join(path, ".")
+
+		if jsonHasField(js, path) {
+			w.Header().Add("Warning", fmt.Sprintf(`299 - "field %s is deprecated"`, field))
+		}
+	}
+}
+
+// jsonHasField reports whether the JSON value js contains the given path of keys.
+func jsonHasField(js []byte, path []string) bool {
+	if len(path) == 0 {
+		return true
+	}
+
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(js, &object); err == nil {
+		value, ok := object[path[0]]
+		return ok && jsonHasField(value, path[1:])
+	}
+
+	var array []json.RawMessage
+	if err := json.Unmarshal(js, &array); err == nil {
+		for _, element := range array {
+			if jsonHasField(element, path) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// jsonAPIPath maps a path into the usual response envelope to the same field in a
+// JSON:API document. The envelope key becomes the primary data, and every field of
+// a resource other than its id is one of its attributes.
+func jsonAPIPath(path []string) []string {
+	switch {
+	case len(path) < 2:
+		return []string{"data"}
+	case path[1] == "id":
+		return append([]string{"data"}, path[1:]...)
+	default:
+		return append([]string{"data", "attributes"}, path[1:]...)
+	}
+}
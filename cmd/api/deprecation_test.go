@@ -0,0 +1,108 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestJSONHasField(t *testing.T) {
+	const js = `{"movie":{"title":"Up","year":2009,"genres":["animation"],"cast":[{"name":"Ed"},{"role":"Carl"}]},"movies":[{"id":1},{"id":2,"year":2009}]}`
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"movie", true},
+		{"movie.year", true},
+		{"movie.runtime", false},
+		{"movie.year.value", false},
+		{"movie.genres", true},
+		{"movie.cast.role", true},
+		{"movie.cast.age", false},
+		{"movies.year", true},
+		{"movies.runtime", false},
+		{"metadata", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := jsonHasField([]byte(js), strings.Split(tt.path, ".")); got != tt.want {
+				t.Errorf("got %t; want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddDeprecationWarnings(t *testing.T) {
+	tests := []struct {
+		name        string
+		fields      []string
+		camel       bool
+		data        envelope
+		contentType string
+		want        []string
+	}{
+		{
+			name:   "Present",
+			fields: []string{"movie.year", "movie.runtime"},
+			data:   envelope{"movie": map[string]any{"title": "Up", "year": 2009}},
+			want:   []string{`299 - "field movie.year is deprecated"`},
+		},
+		{
+			name:   "Absent",
+			fields: []string{"movie.runtime"},
+			data:   envelope{"movie": map[string]any{"title": "Up"}},
+		},
+		{
+			name:   "Array along the path",
+			fields: []string{"movies.year"},
+			data:   envelope{"movies": []any{map[string]any{"id": 1}, map[string]any{"year": 2009}}},
+			want:   []string{`299 - "field movies.year is deprecated"`},
+		},
+		{
+			name:   "Output case",
+			fields: []string{"movie.created_at"},
+			camel:  true,
+			data:   envelope{"movie": map[string]any{"created_at": "2009-05-29T00:00:00Z"}},
+			want:   []string{`299 - "field movie.createdAt is deprecated"`},
+		},
+		{
+			name:        "JSON:API attribute",
+			fields:      []string{"movie.year", "movie.id", "movie.runtime"},
+			data:        envelope{"data": map[string]any{"type": "movies", "id": "1", "attributes": map[string]any{"year": 2009}}},
+			contentType: jsonAPIMediaType,
+			want: []string{
+				`299 - "field data.attributes.year is deprecated"`,
+				`299 - "field data.id is deprecated"`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+			app.config.deprecatedFields = tt.fields
+			if tt.camel {
+				app.config.jsonOutputCase = jsonCaseCamel
+			}
+
+			headers := http.Header{}
+			if tt.contentType != "" {
+				headers.Set("Content-Type", tt.contentType)
+			}
+
+			rr := httptest.NewRecorder()
+			err := app.writeJSON(rr, http.StatusOK, tt.data, headers)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := rr.Header().Values("Warning")
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got warnings %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,8 +1,12 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
+
+	"greenlight/internal/validator"
 )
 
 // The logError() method is a generic helper for logging an error message.
@@ -15,6 +19,11 @@ func (app *application) logError(r *http.Request, err error) {
 func (app *application) errorResponse(w http.ResponseWriter, r *http.Request, status int, message any) {
 	env := envelope{"error": message}
 
+	// Error responses must never be cached, regardless of the policy configured for
+	// the route, so override any caching headers set by the cacheControl() wrapper.
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Del("Expires")
+
 	err := app.writeJSON(w, status, env, nil)
 	if err != nil {
 		app.logError(r, err)
@@ -34,6 +43,27 @@ func (app *application) serverErrorResponse(w http.ResponseWriter, r *http.Reque
 	app.errorResponse(w, r, http.StatusInternalServerError, message)
 }
 
+// The panicResponse() method will be used when recoverPanic() catches a panic. The
+// panic value and stack trace are always logged. The client gets the same opaque
+// 500 response as serverErrorResponse(), unless -expose-panic-detail is set, in
+// which case the detail is included to speed up debugging.
+func (app *application) panicResponse(w http.ResponseWriter, r *http.Request, recovered any, stack []byte) {
+	app.logError(r, fmt.Errorf("panic: %v\n%s", recovered, stack))
+
+	message := "server encountered a problem and could not process your request"
+	if !app.config.exposePanicDetail {
+		app.errorResponse(w, r, http.StatusInternalServerError, message)
+		return
+	}
+
+	detail := map[string]any{
+		"message": message,
+		"panic":   fmt.Sprint(recovered),
+		"stack":   strings.Split(strings.TrimSpace(string(stack)), "\n"),
+	}
+	app.errorResponse(w, r, http.StatusInternalServerError, detail)
+}
+
 // The notFoundResponse() method will be used to send a 404 Not Found status code and
 // JSON response to the client.
 func (app *application) notFoundResponse(w http.ResponseWriter, r *http.Request) {
@@ -52,8 +82,72 @@ func (app *application) badRequestResponse(w http.ResponseWriter, r *http.Reques
 	app.errorResponse(w, r, http.StatusBadRequest, err.Error())
 }
 
-// Note that the errors parameter here has the type map[string]string, which is exactly
-// the same as the errors map contained in our Validator type.
-func (app *application) failedValidationResponse(w http.ResponseWriter, r *http.Request, errors map[string]string) {
-	app.errorResponse(w, r, http.StatusUnprocessableEntity, errors)
+// The payloadTooLargeResponse() method will be used to send a 413 Request Entity Too
+// Large status code and JSON response to the client.
+func (app *application) payloadTooLargeResponse(w http.ResponseWriter, r *http.Request, err error) {
+	app.errorResponse(w, r, http.StatusRequestEntityTooLarge, err.Error())
+}
+
+// The notAcceptableResponse() method will be used to send a 406 Not Acceptable
+// status code and JSON response to the client.
+func (app *application) notAcceptableResponse(w http.ResponseWriter, r *http.Request) {
+	var mediaTypes []string
+	for _, rep := range app.representations() {
+		mediaTypes = append(mediaTypes, rep.mediaType)
+	}
+
+	message := fmt.Sprintf("the requested resource is only available as %s", strings.Join(mediaTypes, ", "))
+	app.errorResponse(w, r, http.StatusNotAcceptable, message)
+}
+
+// The rateLimitExceededResponse() method will be used to send a 429 Too Many
+// Requests status code and JSON response to the client.
+func (app *application) rateLimitExceededResponse(w http.ResponseWriter, r *http.Request) {
+	message := "rate limit exceeded"
+	app.errorResponse(w, r, http.StatusTooManyRequests, message)
+}
+
+// The readOnlyResponse() method will be used to send a 503 Service Unavailable
+// status code and JSON response to the client when a write is attempted while the
+// application is in read-only mode.
+func (app *application) readOnlyResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the service is read-only for maintenance, please try again later"
+	app.errorResponse(w, r, http.StatusServiceUnavailable, message)
+}
+
+// The uriTooLongResponse() method will be used to send a 414 URI Too Long status code
+// and JSON response to the client.
+func (app *application) uriTooLongResponse(w http.ResponseWriter, r *http.Request) {
+	message := fmt.Sprintf("the request URL cannot be longer than %d bytes", app.config.url.maxLength)
+	app.errorResponse(w, r, http.StatusRequestURITooLong, message)
+}
+
+// The readJSONErrorResponse() method sends the appropriate response for an error
+// returned by readJSON(): a failed validation response for an oversized field, 413
+// for an oversized body, and 400 Bad Request for anything else.
+func (app *application) readJSONErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	var fieldErr *fieldTooLargeError
+	var bodyErr *bodyTooLargeError
+
+	switch {
+	case errors.As(err, &fieldErr):
+		app.failedValidationResponse(w, r, map[string]validator.Message{fieldErr.Field: fieldErr.message()})
+	case errors.As(err, &bodyErr):
+		app.payloadTooLargeResponse(w, r, err)
+	default:
+		app.badRequestResponse(w, r, err)
+	}
+}
+
+// The failedValidationResponse() method sends the messages of a failed validation,
+// usually a Validator's Messages map, translated into the client's preferred
+// language; the keys stay the same in every language. The status code is
+// configurable because some API gateways mishandle 422 responses.
+func (app *application) failedValidationResponse(w http.ResponseWriter, r *http.Request, errors map[string]validator.Message) {
+	lang := app.translator.Match(r.Header.Get("Accept-Language"))
+
+	w.Header().Add("Vary", "Accept-Language")
+	w.Header().Set("Content-Language", lang.String())
+
+	app.errorResponse(w, r, app.config.validationStatus, app.translator.TranslateAll(lang, errors))
 }
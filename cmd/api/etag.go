@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+// etagsMatch reports whether etag matches any of the entity tags listed in the
+// given If-Match or If-None-Match header values, following RFC 7232 section 2.3.2.
+// With weak comparison the W/ prefix is ignored on both sides, which is what
+// If-None-Match uses. With strong comparison, as If-Match uses, both tags must be
+// strong and identical. The "*" wildcard always matches.
+func etagsMatch(headerValues []string, etag string, weak bool) bool {
+	for _, candidate := range parseETags(strings.Join(headerValues, ",")) {
+		if candidate == "*" {
+			return true
+		}
+
+		if weak {
+			if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+			continue
+		}
+
+		if !strings.HasPrefix(candidate, "W/") && !strings.HasPrefix(etag, "W/") && candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// parseETags splits a comma-separated list of entity tags. Commas are allowed
+// inside the quoted part of a tag, so we can't simply split on them.
+func parseETags(list string) []string {
+	var tags []string
+
+	for {
+		list = strings.TrimLeft(list, " \t,")
+		if list == "" {
+			return tags
+		}
+
+		if list[0] == '*' {
+			tags = append(tags, "*")
+			list = list[1:]
+			continue
+		}
+
+		prefix := ""
+		if strings.HasPrefix(list, "W/") {
+			prefix, list = "W/", list[2:]
+		}
+
+		// Anything that isn't a quoted tag is malformed, so ignore the rest of the
+		// header rather than guess at its meaning.
+		if len(list) < 2 || list[0] != '"' {
+			return tags
+		}
+		end := strings.IndexByte(list[1:], '"')
+		if end < 0 {
+			return tags
+		}
+
+		tags = append(tags, prefix+list[:end+2])
+		list = list[end+2:]
+	}
+}
+
+// notModified sends a 304 Not Modified response if the request's If-None-Match
+// header matches etag, and reports whether it did.
+func notModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	values := r.Header.Values("If-None-Match")
+	if len(values) == 0 || !etagsMatch(values, etag, true) {
+		return false
+	}
+
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseETags(t *testing.T) {
+	tests := []struct {
+		name string
+		list string
+		want []string
+	}{
+		{"Single", `"1-1"`, []string{`"1-1"`}},
+		{"Weak", `W/"1-1"`, []string{`W/"1-1"`}},
+		{"List", `"a", W/"b" ,"c"`, []string{`"a"`, `W/"b"`, `"c"`}},
+		{"Quoted comma", `"a,b", "c"`, []string{`"a,b"`, `"c"`}},
+		{"Wildcard", `*`, []string{"*"}},
+		{"Empty", ``, nil},
+		{"Unquoted", `abc, "d"`, nil},
+		{"Malformed after valid", `"a", b, "c"`, []string{`"a"`}},
+		{"Unterminated", `"a", "b`, []string{`"a"`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseETags(tt.list)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestETagsMatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		etag   string
+		weak   bool
+		want   bool
+	}{
+		{"Weak: strong and strong", `"1-1"`, `"1-1"`, true, true},
+		{"Weak: weak header, strong tag", `W/"1-1"`, `"1-1"`, true, true},
+		{"Weak: strong header, weak tag", `"1-1"`, `W/"1-1"`, true, true},
+		{"Weak: weak and weak", `W/"1-1"`, `W/"1-1"`, true, true},
+		{"Weak: different", `W/"1-2"`, `"1-1"`, true, false},
+		{"Strong: strong and strong", `"1-1"`, `"1-1"`, false, true},
+		{"Strong: weak header, strong tag", `W/"1-1"`, `"1-1"`, false, false},
+		{"Strong: strong header, weak tag", `"1-1"`, `W/"1-1"`, false, false},
+		{"Strong: weak and weak", `W/"1-1"`, `W/"1-1"`, false, false},
+		{"Strong: different", `"1-2"`, `"1-1"`, false, false},
+		{"In a list", `"a", W/"1-1"`, `"1-1"`, true, true},
+		{"Quoted comma is not a separator", `"1-1,x"`, `"1-1"`, true, false},
+		{"Wildcard, weak", `*`, `"1-1"`, true, true},
+		{"Wildcard, strong", `*`, `W/"1-1"`, false, true},
+		{"Malformed", `1-1`, `"1-1"`, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := etagsMatch([]string{tt.header}, tt.etag, tt.weak)
+			if got != tt.want {
+				t.Errorf("etagsMatch(%q, %q, weak=%t) = %t; want %t", tt.header, tt.etag, tt.weak, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestETagsMatchMultipleHeaders(t *testing.T) {
+	if !etagsMatch([]string{`"a"`, `"1-1"`}, `"1-1"`, true) {
+		t.Error("got no match for a tag in the second header value")
+	}
+}
@@ -1,9 +1,14 @@
 package main
 
 import (
+	"context"
 	"net/http"
+	"time"
 )
 
+// healtcheckHandler is the liveness probe. It always reports 200 while the process
+// can serve requests at all. Whether we are draining before a shutdown is shown in
+// the body, but it is /v1/readyz that fails, to move traffic elsewhere.
 func (app *application) healtcheckHandler(w http.ResponseWriter, r *http.Request) {
 	env := envelope{
 		"status": "available",
@@ -11,6 +16,13 @@ func (app *application) healtcheckHandler(w http.ResponseWriter, r *http.Request
 			"environment": app.config.env,
 			"version":     version,
 		},
+		"read_only": app.readOnly.Load(),
+		"draining":  app.draining.Load(),
+	}
+
+	// Outside of production, report which experimental features are switched on.
+	if app.config.env != "production" {
+		env["features"] = app.features.Active()
 	}
 
 	err := app.writeJSON(w, http.StatusOK, env, nil)
@@ -19,3 +31,69 @@ func (app *application) healtcheckHandler(w http.ResponseWriter, r *http.Request
 		app.serverErrorResponse(w, r, err)
 	}
 }
+
+// readyzHandler is the readiness probe. Unlike the healthcheck, which only shows
+// that the process is up, it reports 200 only while we can actually serve traffic:
+// the database pool is reachable and its schema was verified at startup (or the check
+// was deliberately skipped). It reports 503 as soon as we start draining, so that
+// traffic is moved elsewhere before connections are closed.
+func (app *application) readyzHandler(w http.ResponseWriter, r *http.Request) {
+	env := envelope{"status": "ready"}
+	code := http.StatusOK
+
+	if app.draining.Load() {
+		env["status"], code = "draining", http.StatusServiceUnavailable
+	} else {
+		checks := map[string]string{
+			"database": "ok",
+			"schema":   "ok",
+		}
+		if app.config.skipSchemaCheck {
+			checks["schema"] = "skipped"
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+		defer cancel()
+
+		if app.db == nil || app.db.PingContext(ctx) != nil {
+			checks["database"] = "unavailable"
+			env["status"], code = "not ready", http.StatusServiceUnavailable
+		}
+		env["checks"] = checks
+	}
+
+	err := app.writeJSON(w, code, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// rootHandler identifies the service to browsers and probes that hit the API root,
+// rather than sending them a 404.
+func (app *application) rootHandler(w http.ResponseWriter, r *http.Request) {
+	env := envelope{
+		"name":    "greenlight",
+		"version": version,
+		"docs":    "https://github.com/thannoz/greenlight",
+	}
+
+	err := app.writeJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// faviconHandler answers browser favicon requests with an empty 204 response.
+func (app *application) faviconHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// debugVarsHandler reports the number of requests in flight. Unlike the standard
+// expvar handler it publishes nothing else, since the command line and memory
+// statistics it would expose include the database DSN.
+func (app *application) debugVarsHandler(w http.ResponseWriter, r *http.Request) {
+	err := app.writeJSON(w, http.StatusOK, envelope{"requests_in_flight": app.inFlight.count()}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestHealthcheck(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+
+	code, headers, body := ts.get(t, "/v1/healthcheck")
+
+	if code != http.StatusOK {
+		t.Errorf("got status %d; want %d", code, http.StatusOK)
+	}
+	if got := headers.Get("Cache-Control"); got != "no-store" {
+		t.Errorf("got Cache-Control %q; want %q", got, "no-store")
+	}
+
+	var status string
+	decodeEnvelope(t, body, "status", &status)
+	if status != "available" {
+		t.Errorf("got status %q; want %q", status, "available")
+	}
+	var draining bool
+	decodeEnvelope(t, body, "draining", &draining)
+	if draining {
+		t.Error("got draining true; want false")
+	}
+}
+
+func TestProbesWhileDraining(t *testing.T) {
+	app := newTestApplication(t)
+	app.draining.Store(true)
+	ts := newTestServer(t, app.routes())
+
+	code, _, body := ts.get(t, "/v1/healthcheck")
+	if code != http.StatusOK {
+		t.Errorf("got healthcheck status %d; want %d", code, http.StatusOK)
+	}
+	var status string
+	decodeEnvelope(t, body, "status", &status)
+	if status != "available" {
+		t.Errorf("got healthcheck status %q; want %q", status, "available")
+	}
+	var draining bool
+	decodeEnvelope(t, body, "draining", &draining)
+	if !draining {
+		t.Error("got draining false in the healthcheck; want true")
+	}
+
+	code, _, body = ts.get(t, "/v1/readyz")
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("got readyz status %d; want %d", code, http.StatusServiceUnavailable)
+	}
+	decodeEnvelope(t, body, "status", &status)
+	if status != "draining" {
+		t.Errorf("got readyz status %q; want %q", status, "draining")
+	}
+}
+
+func TestDebugVars(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+
+	code, _, body := ts.get(t, "/debug/vars")
+	if code != http.StatusOK {
+		t.Fatalf("got status %d; want %d", code, http.StatusOK)
+	}
+
+	var vars map[string]json.RawMessage
+	err := json.Unmarshal(body, &vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vars) != 1 {
+		t.Errorf("got vars %q; want only requests_in_flight", body)
+	}
+
+	// The request for /debug/vars is itself in flight.
+	var inFlight int
+	decodeEnvelope(t, body, "requests_in_flight", &inFlight)
+	if inFlight != 1 {
+		t.Errorf("got %d requests in flight; want 1", inFlight)
+	}
+}
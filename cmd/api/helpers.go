@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -11,12 +12,40 @@ import (
 	"strconv"
 	"strings"
 
+	"greenlight/internal/validator"
+
 	"github.com/joho/godotenv"
 	"github.com/julienschmidt/httprouter"
 )
 
 type envelope map[string]any
 
+// fieldTooLargeError is returned by readJSON() when a single string value in the
+// request body is larger than the configured maximum field size.
+type fieldTooLargeError struct {
+	Field string
+	Limit int
+}
+
+func (e *fieldTooLargeError) Error() string {
+	return e.message().String()
+}
+
+// message returns the validation error message for the field, for translation.
+func (e *fieldTooLargeError) message() validator.Message {
+	return validator.Message{Template: "must not be more than %d bytes long", Args: []any{e.Limit}}
+}
+
+// bodyTooLargeError is returned by readJSON() when the request body is larger than
+// the configured maximum body size.
+type bodyTooLargeError struct {
+	Limit int64
+}
+
+func (e *bodyTooLargeError) Error() string {
+	return fmt.Sprintf("body cannot be larger than %d bytes", e.Limit)
+}
+
 func (app *application) readIDParam(r *http.Request) (int64, error) {
 	params := httprouter.ParamsFromContext(r.Context())
 
@@ -28,6 +57,11 @@ func (app *application) readIDParam(r *http.Request) (int64, error) {
 	return id, nil
 }
 
+// feature returns true if the named experimental feature is enabled.
+func (app *application) feature(name string) bool {
+	return app.features.Enabled(name)
+}
+
 // writeJSON sends responses & takes the destination
 // http.ResponseWriter, the HTTP status code to send, the data to encode to JSON, and a
 // header map containing any additional HTTP headers we want to include in the response.
@@ -37,6 +71,11 @@ func (app *application) writeJSON(w http.ResponseWriter, status int, data envelo
 		return err
 	}
 
+	js, err = app.applyOutputCase(js)
+	if err != nil {
+		return err
+	}
+
 	js = append(js, '\n')
 
 	// At this point, we know that we won't encounter any more errors before writing the
@@ -46,9 +85,13 @@ func (app *application) writeJSON(w http.ResponseWriter, status int, data envelo
 		w.Header()[key] = value
 	}
 
-	// Add the "Content-Type: application/json" header, then write the status code and
-	// JSON response.
-	w.Header().Set("Content-Type", "application/json")
+	app.addDeprecationWarnings(w, js)
+
+	// Add the "Content-Type: application/json" header (unless a more specific JSON
+	// media type has already been set), then write the status code and JSON response.
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
 	w.WriteHeader(status)
 	w.Write(js)
 
@@ -57,20 +100,61 @@ func (app *application) writeJSON(w http.ResponseWriter, status int, data envelo
 
 // readJSON decodes the JSON from the request body
 func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any) error {
-	maxBites := 1_048_576
-	r.Body = http.MaxBytesReader(w, r.Body, int64(maxBites))
+	// Reject a body that declares itself too large up front, rather than reading it
+	// only to fail at the limit. Bodies without a Content-Length, such as chunked
+	// ones, are still bounded by MaxBytesReader below.
+	if r.ContentLength > app.config.body.maxBytes {
+		return &bodyTooLargeError{Limit: app.config.body.maxBytes}
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, app.config.body.maxBytes)
+
+	// Read the whole (size-limited) body up front so that it can be scanned for
+	// oversized fields before we spend any effort decoding it into dst.
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		var maxBitesError *http.MaxBytesError
+		if errors.As(err, &maxBitesError) {
+			return &bodyTooLargeError{Limit: maxBitesError.Limit}
+		}
+		return err
+	}
+
+	err = checkJSONLimits(body, app.config.body.maxFieldBytes, app.config.body.maxDepth)
+	if err != nil {
+		return err
+	}
+
+	// Fill in any struct-tag defaults before decoding, so that the client's values
+	// take precedence. A bad default tag is a bug in our code, so we panic.
+	err = applyDefaults(dst)
+	if err != nil {
+		panic(err)
+	}
 
-	dec := json.NewDecoder(r.Body)
+	// If the destination accepts aliases for some of its keys, or we accept camelCase
+	// keys, rewrite them to the canonical names so that the strict decoder below
+	// accepts them.
+	var aliases map[string]string
+	if aliaser, ok := dst.(jsonAliaser); ok {
+		aliases = aliaser.JSONAliases()
+	}
+
+	body, err = app.normalizeInputKeys(body, aliases)
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
 	dec.DisallowUnknownFields()
 
 	// Decode the request body into the target destination
-	err := dec.Decode(dst)
+	err = dec.Decode(dst)
 	if err != nil {
 		// If there is an error during decoding, start the triage process
 		var syntaxError *json.SyntaxError
 		var unmarshalTypeError *json.UnmarshalTypeError
 		var invalidUnmarshalError *json.InvalidUnmarshalError
-		var maxBitesError *http.MaxBytesError
 
 		switch {
 		// Check whether the error has the type *json.SyntaxError
@@ -94,13 +178,9 @@ func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any
 
 		// Check for field name that cannot be mapped to the target destination
 		case strings.HasPrefix(err.Error(), "json: unknown field"):
-			fieldName := strings.TrimPrefix(err.Error(), "json: unknown field")
+			fieldName := strings.TrimPrefix(err.Error(), "json: unknown field ")
 			return fmt.Errorf("body contains unknown key %s", fieldName)
 
-		// Check whether the error has a type of *http.MaxBytesError
-		case errors.As(err, &maxBitesError):
-			return fmt.Errorf("body cannot be larger than %d bytes", maxBitesError.Limit)
-
 		// This error is returned when we pass something that is not a non-nil pointer to Decode-method.
 		case errors.As(err, &invalidUnmarshalError):
 			panic(err)
@@ -122,6 +202,90 @@ func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any
 	return nil
 }
 
+// The jsonAliaser interface can be implemented by readJSON() destinations that accept
+// alternative spellings of their keys, such as camelCase versions of our snake_case
+// fields. JSONAliases() returns a map of alias to canonical key. Types that don't
+// implement it get the default strict, snake_case only behavior.
+type jsonAliaser interface {
+	JSONAliases() map[string]string
+}
+
+// checkJSONLimits walks the tokens of a JSON body and returns a *fieldTooLargeError
+// for the first string value longer than maxFieldBytes, or an error if objects and
+// arrays are nested more than maxDepth levels deep. Malformed JSON is ignored here
+// and left for the decoder to report.
+func checkJSONLimits(body []byte, maxFieldBytes, maxDepth int) error {
+	// Each frame tracks an object or array we are currently inside. For objects we
+	// remember the most recent key, and whether the next string token is a key.
+	type frame struct {
+		object    bool
+		key       string
+		expectKey bool
+	}
+	var stack []*frame
+
+	valueDone := func() {
+		if n := len(stack); n > 0 && stack[n-1].object {
+			stack[n-1].expectKey = true
+		}
+	}
+
+	fieldName := func() string {
+		for i := len(stack) - 1; i >= 0; i-- {
+			if stack[i].object && stack[i].key != "" {
+				return stack[i].key
+			}
+		}
+		return "body"
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil
+		}
+
+		switch t := tok.(type) {
+		case json.Delim:
+			switch t {
+			case '{', '[':
+				stack = append(stack, &frame{object: t == '{', expectKey: t == '{'})
+				if len(stack) > maxDepth {
+					return errors.New("body is too deeply nested")
+				}
+			case '}', ']':
+				stack = stack[:len(stack)-1]
+				valueDone()
+			}
+		case string:
+			if n := len(stack); n > 0 && stack[n-1].object && stack[n-1].expectKey {
+				stack[n-1].key = t
+				stack[n-1].expectKey = false
+				continue
+			}
+			if len(t) > maxFieldBytes {
+				return &fieldTooLargeError{Field: fieldName(), Limit: maxFieldBytes}
+			}
+			valueDone()
+		default:
+			valueDone()
+		}
+	}
+}
+
+// splitList splits a comma-separated list, trimming whitespace and dropping empty
+// entries.
+func splitList(val string) []string {
+	list := []string{}
+	for _, item := range strings.Split(val, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
 // getStrEnv reads from the environment variables & returns it as string
 func getStrEnv(key string) string {
 	envErr := godotenv.Load(".env")
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// nested returns a JSON body with depth levels of arrays nested inside an object.
+func nested(depth int) string {
+	return `{"genres":` + strings.Repeat("[", depth-1) + strings.Repeat("]", depth-1) + `}`
+}
+
+func TestCheckJSONLimitsDepth(t *testing.T) {
+	const maxDepth = 32
+
+	tests := []struct {
+		name    string
+		body    string
+		wantErr bool
+	}{
+		{"Under the limit", nested(maxDepth - 1), false},
+		{"At the limit", nested(maxDepth), false},
+		{"Over the limit", nested(maxDepth + 1), true},
+		{"Over the limit with objects", strings.Repeat(`{"a":`, maxDepth+1) + "1" + strings.Repeat("}", maxDepth+1), true},
+		{"Malformed", strings.Repeat("[", maxDepth), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkJSONLimits([]byte(tt.body), 4096, maxDepth)
+
+			if tt.wantErr {
+				if err == nil || err.Error() != "body is too deeply nested" {
+					t.Errorf("got error %v; want %q", err, "body is too deeply nested")
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("got error %v; want none", err)
+			}
+		})
+	}
+}
+
+func TestCheckJSONLimitsFieldSize(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		wantField string
+	}{
+		{"At the limit", `{"title":"` + strings.Repeat("a", 10) + `"}`, ""},
+		{"Over the limit", `{"title":"` + strings.Repeat("a", 11) + `"}`, "title"},
+		{"Long key", `{"` + strings.Repeat("a", 11) + `":"x"}`, ""},
+		{"In an array", `{"genres":["a","` + strings.Repeat("a", 11) + `"]}`, "genres"},
+		{"Top level", `"` + strings.Repeat("a", 11) + `"`, "body"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkJSONLimits([]byte(tt.body), 10, 32)
+
+			var fieldErr *fieldTooLargeError
+			switch {
+			case tt.wantField == "" && err != nil:
+				t.Errorf("got error %v; want none", err)
+			case tt.wantField != "" && !errors.As(err, &fieldErr):
+				t.Errorf("got error %v; want a *fieldTooLargeError", err)
+			case tt.wantField != "" && fieldErr.Field != tt.wantField:
+				t.Errorf("got field %q; want %q", fieldErr.Field, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestNormalizeInputKeys(t *testing.T) {
+	aliases := map[string]string{"name": "title"}
+
+	tests := []struct {
+		name    string
+		camel   bool
+		aliases map[string]string
+		body    string
+		want    string
+		wantErr string
+	}{
+		{"Nothing to do", false, nil, `{"createdAt":1}`, `{"createdAt":1}`, ""},
+		{"Alias", false, aliases, `{"name":"Up","year":2009}`, `{"title":"Up","year":2009}`, ""},
+		{"Camel case at any depth", true, nil, `{"releaseInfo":{"releaseYear":2009},"list":[{"someKey":1.50}]}`, `{"release_info":{"release_year":2009},"list":[{"some_key":1.50}]}`, ""},
+		{"Snake case still accepted", true, nil, `{"created_at":1}`, `{"created_at":1}`, ""},
+		{"Alias and camel case", true, aliases, `{"name":"Up","runTime":96}`, `{"title":"Up","run_time":96}`, ""},
+		{"Alias clash", false, aliases, `{"name":"Up","title":"Up"}`, "", `body contains both "name" and "title"`},
+		{"Camel case clash", true, nil, `{"created_at":1,"createdAt":2}`, "", `body contains both "created_at" and "createdAt"`},
+		{"Malformed", true, aliases, `{"name":`, `{"name":`, ""},
+		{"Trailing data", true, aliases, `{"name":"Up"}{}`, `{"name":"Up"}{}`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+			if tt.camel {
+				app.config.jsonOutputCase = jsonCaseCamel
+			}
+
+			got, err := app.normalizeInputKeys([]byte(tt.body), tt.aliases)
+
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("got error %v; want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got error %v; want none", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %s; want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// aliasedInput accepts "name" as an alias for "title".
+type aliasedInput struct {
+	Title string `json:"title"`
+	Year  int32  `json:"year"`
+}
+
+func (aliasedInput) JSONAliases() map[string]string {
+	return map[string]string{"name": "title"}
+}
+
+// plainInput has the same fields as aliasedInput but no aliases.
+type plainInput struct {
+	Title string `json:"title"`
+	Year  int32  `json:"year"`
+}
+
+func TestReadJSONAliases(t *testing.T) {
+	tests := []struct {
+		name      string
+		aliased   bool
+		body      string
+		wantTitle string
+		wantErr   string
+	}{
+		{"Alias", true, `{"name":"Up","year":2009}`, "Up", ""},
+		{"Canonical key", true, `{"title":"Up","year":2009}`, "Up", ""},
+		{"Alias and canonical key", true, `{"name":"Up","title":"Up"}`, "", `body contains both "name" and "title"`},
+		{"Unknown key with aliases", true, `{"name":"Up","rating":5}`, "", `body contains unknown key "rating"`},
+		{"Alias without aliases", false, `{"name":"Up"}`, "", `body contains unknown key "name"`},
+		{"Canonical key without aliases", false, `{"title":"Up","year":2009}`, "Up", ""},
+		{"Malformed with aliases", true, `{"name":`, "", "body contains badly-formed JSON"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+
+			var title string
+			var err error
+			if tt.aliased {
+				var input aliasedInput
+				err = app.readJSON(httptest.NewRecorder(), req, &input)
+				title = input.Title
+			} else {
+				var input plainInput
+				err = app.readJSON(httptest.NewRecorder(), req, &input)
+				title = input.Title
+			}
+
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("got error %v; want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got error %v; want none", err)
+			}
+			if title != tt.wantTitle {
+				t.Errorf("got title %q; want %q", title, tt.wantTitle)
+			}
+		})
+	}
+}
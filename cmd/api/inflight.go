@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// inFlightRequests tracks the requests that are currently being handled, so that
+// their number (and during shutdown, their routes) can be reported. The zero value
+// is ready to use and it is safe for concurrent use.
+type inFlightRequests struct {
+	mu     sync.Mutex
+	nextID uint64
+	active map[uint64]string
+}
+
+// add records the start of a request to route and returns an id to pass to done().
+func (f *inFlightRequests) add(route string) uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.active == nil {
+		f.active = make(map[uint64]string)
+	}
+
+	f.nextID++
+	f.active[f.nextID] = route
+	return f.nextID
+}
+
+// done records that the request with the given id has finished.
+func (f *inFlightRequests) done(id uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.active, id)
+}
+
+// count returns the number of requests currently in flight.
+func (f *inFlightRequests) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return len(f.active)
+}
+
+// wait blocks until no requests are in flight, or returns ctx.Err() if ctx is done
+// first.
+func (f *inFlightRequests) wait(ctx context.Context) error {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for f.count() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// routes returns a sorted summary of the in-flight requests, one entry per route
+// with the number of requests to it, e.g. "GET /v1/movie/1 (x2)".
+func (f *inFlightRequests) routes() []string {
+	f.mu.Lock()
+	counts := make(map[string]int)
+	for _, route := range f.active {
+		counts[route]++
+	}
+	f.mu.Unlock()
+
+	summary := make([]string, 0, len(counts))
+	for route, n := range counts {
+		summary = append(summary, fmt.Sprintf("%s (x%d)", route, n))
+	}
+	sort.Strings(summary)
+	return summary
+}
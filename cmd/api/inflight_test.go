@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestInFlightRequestsWait(t *testing.T) {
+	var f inFlightRequests
+
+	// Nothing in flight returns straight away.
+	err := f.wait(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id := f.add("GET /v1/movie/1")
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		f.done(id)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	err = f.wait(ctx)
+	if err != nil {
+		t.Errorf("got error %v waiting for a finished request; want none", err)
+	}
+
+	f.add("POST /v1/movies")
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err = f.wait(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v waiting for a stuck request; want %v", err, context.DeadlineExceeded)
+	}
+	if got := f.routes(); len(got) != 1 || got[0] != "POST /v1/movies (x1)" {
+		t.Errorf("got routes %q; want the stuck request", got)
+	}
+}
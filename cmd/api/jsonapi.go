@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"greenlight/internal/data"
+)
+
+const jsonAPIMediaType = "application/vnd.api+json"
+
+// The jsonAPIResource type is a JSON:API resource object. See
+// https://jsonapi.org/format/#document-resource-objects.
+type jsonAPIResource struct {
+	Type       string         `json:"type"`
+	ID         string         `json:"id"`
+	Attributes map[string]any `json:"attributes"`
+}
+
+// movieResource converts a movie to a JSON:API resource object. The attributes are
+// the movie's usual JSON fields, minus the id which is hoisted to the top level.
+func movieResource(movie *data.Movie) (jsonAPIResource, error) {
+	js, err := json.Marshal(movie)
+	if err != nil {
+		return jsonAPIResource{}, err
+	}
+
+	var attributes map[string]any
+	err = json.Unmarshal(js, &attributes)
+	if err != nil {
+		return jsonAPIResource{}, err
+	}
+	delete(attributes, "id")
+
+	return jsonAPIResource{
+		Type:       "movies",
+		ID:         strconv.FormatInt(movie.ID, 10),
+		Attributes: attributes,
+	}, nil
+}
+
+// writeJSONAPI sends a JSON:API document with the given top-level members.
+func (app *application) writeJSONAPI(w http.ResponseWriter, status int, doc envelope, headers http.Header) error {
+	// Pass the media type through the headers map, so that it is only applied once
+	// writeJSON() knows the document encoded successfully.
+	headers = headers.Clone()
+	if headers == nil {
+		headers = make(http.Header)
+	}
+	headers.Set("Content-Type", jsonAPIMediaType)
+
+	return app.writeJSON(w, status, doc, headers)
+}
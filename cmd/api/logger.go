@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+)
+
+// The newLogger() function builds the application logger from the -log-format and
+// -log-output settings. Log lines are written through a slog handler, either JSON or
+// text, but it is exposed as a *log.Logger so that the rest of the application can
+// keep using the familiar Print and Fatal methods. If the logs go to a file, the
+// opened file is returned so that the caller can close it.
+func newLogger(cfg config) (*log.Logger, *os.File, error) {
+	var out io.Writer
+	var file *os.File
+
+	switch cfg.log.output {
+	case "stdout":
+		out = os.Stdout
+	case "stderr":
+		out = os.Stderr
+	case "discard":
+		out = io.Discard
+	default:
+		// Anything else is a file path. Open it for appending so that restarts don't
+		// truncate earlier logs.
+		f, err := os.OpenFile(cfg.log.output, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("opening log file: %w", err)
+		}
+		out, file = f, f
+	}
+
+	var handler slog.Handler
+	switch cfg.log.format {
+	case "json":
+		handler = slog.NewJSONHandler(out, nil)
+	case "text":
+		handler = slog.NewTextHandler(out, nil)
+	default:
+		if file != nil {
+			file.Close()
+		}
+		return nil, nil, fmt.Errorf("invalid -log-format %q: must be json or text", cfg.log.format)
+	}
+
+	return slog.NewLogLogger(handler, slog.LevelInfo), file, nil
+}
@@ -3,51 +3,298 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"strings"
+	"sync/atomic"
 	"time"
 
+	"greenlight/internal/data"
+	"greenlight/internal/flags"
+	"greenlight/internal/i18n"
+	"greenlight/internal/validator"
+
 	_ "github.com/lib/pq"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
 const version = "1.0.0"
 
 // Define a config struct to hold all the configuration settings for our application.
 type config struct {
-	port int
-	env  string
-	db   struct {
+	port               int
+	env                string
+	preflight          bool
+	skipSchemaCheck    bool
+	h2c                bool
+	maxConnections     int
+	canonicalHost      string
+	canonicalScheme    string
+	deprecatedFields   []string
+	readOnly           bool
+	weakETags          bool
+	shutdownDrainDelay time.Duration
+	features           string
+	validationStatus   int
+	timeFormat         string
+	emptyArrayBehavior string
+	exposePanicDetail  bool
+	titleDenyList      string
+	jsonOutputCase     string
+	db                 struct {
 		dsn          string
 		maxOpenConns int
 		maxIdleConns int
 		maxIdleTime  string
 	}
+	log struct {
+		format string
+		output string
+	}
+	genres struct {
+		min int
+		max int
+	}
+	limiter struct {
+		enabled bool
+		rps     float64
+		burst   int
+	}
+	tracing struct {
+		endpoint    string
+		sampleRatio float64
+	}
+	cors struct {
+		trustedOrigins   []string
+		allowCredentials bool
+		allowHeaders     []string
+		exposeHeaders    []string
+		maxAge           time.Duration
+	}
+	securityHeaders map[string]string
+	url             struct {
+		maxLength      int
+		maxQueryParams int
+	}
+	body struct {
+		maxBytes      int64
+		maxFieldBytes int
+		maxDepth      int
+	}
 }
 
 // Define an application struct to hold the dependencies for our HTTP handlers, helpers,
 // and middleware.
 type application struct {
-	config config
-	logger *log.Logger
+	config     config
+	logger     *log.Logger
+	db         *sql.DB
+	features   *flags.Flags
+	translator *i18n.Translator
+	draining   atomic.Bool
+	readOnly   atomic.Bool
+	inFlight   inFlightRequests
+	limiter    *ipRateLimiter
+	// tracerProvider is nil unless tracing is enabled.
+	tracerProvider *sdktrace.TracerProvider
 }
 
-func main() {
+// defaultConfig returns the configuration used when no flags are given. The flags
+// take their defaults from it, and tests start from it too.
+func defaultConfig() config {
 	var cfg config
 
+	cfg.env = "development"
+	cfg.db.maxOpenConns = 25
+	cfg.db.maxIdleConns = 25
+	cfg.db.maxIdleTime = "15m"
+	cfg.genres.min = 1
+	cfg.genres.max = 5
+	cfg.log.format = "text"
+	cfg.log.output = "stdout"
+	cfg.canonicalScheme = "https"
+	cfg.validationStatus = http.StatusUnprocessableEntity
+	cfg.timeFormat = data.TimeFormatRFC3339
+	cfg.emptyArrayBehavior = data.EmptyArrayOmit
+	cfg.jsonOutputCase = jsonCaseSnake
+	cfg.limiter.enabled = true
+	cfg.limiter.rps = 2
+	cfg.limiter.burst = 4
+	cfg.tracing.sampleRatio = 1
+	cfg.cors.allowHeaders = []string{"Authorization", "Content-Type"}
+	cfg.securityHeaders = map[string]string{
+		"Content-Security-Policy": "default-src 'none'; frame-ancestors 'none'",
+		"X-Content-Type-Options":  "nosniff",
+		"X-Frame-Options":         "DENY",
+	}
+	cfg.url.maxLength = 4096
+	cfg.url.maxQueryParams = 64
+	cfg.body.maxBytes = 1_048_576
+	cfg.body.maxFieldBytes = 4096
+	cfg.body.maxDepth = 32
+
+	return cfg
+}
+
+func main() {
+	cfg := defaultConfig()
+
 	flag.IntVar(&cfg.port, "port", getIntEnv("PORT"), "API server port")
 	flag.StringVar(&cfg.db.dsn, "dsn", getStrEnv("DSN"), "PostgreSQL DSN")
-	flag.StringVar(&cfg.env, "env", "development", "Environment (development|staging|production)")
+	flag.StringVar(&cfg.env, "env", cfg.env, "Environment (development|staging|production)")
+
+	flag.IntVar(&cfg.db.maxOpenConns, "db-max-open-conns", cfg.db.maxOpenConns, "PostgreSQL max open connections")
+	flag.IntVar(&cfg.db.maxIdleConns, "db-max-idle-conns", cfg.db.maxIdleConns, "PostgreSQL max idle connections")
+	flag.StringVar(&cfg.db.maxIdleTime, "db-max-idle-time", cfg.db.maxIdleTime, "PostgreSQL max connection idle time")
+
+	flag.IntVar(&cfg.genres.min, "genres-min", cfg.genres.min, "Minimum number of genres per movie")
+	flag.IntVar(&cfg.genres.max, "genres-max", cfg.genres.max, "Maximum number of genres per movie")
+
+	flag.StringVar(&cfg.log.format, "log-format", cfg.log.format, "Log format (json|text)")
+	flag.StringVar(&cfg.log.output, "log-output", cfg.log.output, "Log destination (stdout|stderr|discard|<file path>)")
+
+	flag.BoolVar(&cfg.readOnly, "read-only", cfg.readOnly, "Start in read-only mode, rejecting all writes with 503")
+	flag.BoolVar(&cfg.weakETags, "etag-weak", cfg.weakETags, "Send weak (W/) rather than strong entity tags")
+	flag.BoolVar(&cfg.h2c, "h2c", cfg.h2c, "Accept HTTP/2 over cleartext (h2c) connections")
+	flag.StringVar(&cfg.canonicalHost, "canonical-host", cfg.canonicalHost, "Redirect requests for any other Host to this host (e.g. api.example.com)")
+	flag.StringVar(&cfg.canonicalScheme, "canonical-scheme", cfg.canonicalScheme, "Scheme for canonical host redirects (http|https)")
+	flag.IntVar(&cfg.maxConnections, "max-connections", cfg.maxConnections, "Maximum number of simultaneous client connections (0 means unlimited)")
+	flag.DurationVar(&cfg.shutdownDrainDelay, "shutdown-drain-delay", cfg.shutdownDrainDelay, "How long to fail readiness checks before shutting down on SIGTERM")
+	flag.BoolVar(&cfg.skipSchemaCheck, "skip-schema-check", cfg.skipSchemaCheck, "Start even if the database schema version doesn't match this build")
+	flag.BoolVar(&cfg.preflight, "preflight", cfg.preflight, "Check the configuration and database, then exit without starting the server")
+	flag.IntVar(&cfg.validationStatus, "validation-status", cfg.validationStatus, "Status code for failed validation responses (400|422)")
+	flag.StringVar(&cfg.timeFormat, "time-format", cfg.timeFormat, "JSON format for timestamps (rfc3339|rfc3339ms|unix)")
+	flag.StringVar(&cfg.emptyArrayBehavior, "empty-array-behavior", cfg.emptyArrayBehavior, "How to encode a movie with no genres (empty|null|omit)")
+	flag.StringVar(&cfg.jsonOutputCase, "json-output-case", cfg.jsonOutputCase, "Key case for JSON responses and accepted in request bodies (snake|camel)")
+	flag.BoolVar(&cfg.exposePanicDetail, "expose-panic-detail", cfg.exposePanicDetail, "Include panic messages and stack traces in 500 responses (never allowed in production)")
+	flag.Func("deprecated-fields", "Response fields to send deprecation warnings for (comma separated paths, e.g. movie.year)", func(val string) error {
+		cfg.deprecatedFields = splitList(val)
+		return nil
+	})
+	flag.StringVar(&cfg.titleDenyList, "title-deny-list", cfg.titleDenyList, "File of words and phrases that movie titles may not contain, one per line (reloaded on SIGHUP)")
+	flag.StringVar(&cfg.features, "features", cfg.features, "Comma-separated list of experimental features to enable (e.g. jsonapi)")
+
+	flag.BoolVar(&cfg.limiter.enabled, "limiter-enabled", cfg.limiter.enabled, "Enable per-IP rate limiting on sensitive endpoints")
+	flag.Float64Var(&cfg.limiter.rps, "limiter-rps", cfg.limiter.rps, "Rate limiter maximum requests per second")
+	flag.IntVar(&cfg.limiter.burst, "limiter-burst", cfg.limiter.burst, "Rate limiter maximum burst")
+
+	flag.StringVar(&cfg.tracing.endpoint, "tracing-endpoint", cfg.tracing.endpoint, "OTLP/HTTP collector URL to export OpenTelemetry traces to, e.g. http://localhost:4318 (empty disables tracing)")
+	flag.Float64Var(&cfg.tracing.sampleRatio, "tracing-sample-ratio", cfg.tracing.sampleRatio, "Fraction of new traces to sample, from 0 to 1")
 
-	flag.IntVar(&cfg.db.maxOpenConns, "db-max-open-conns", 25, "PostgreSQL max open connections")
-	flag.IntVar(&cfg.db.maxIdleConns, "db-max-idle-conns", 25, "PostgreSQL max idle connections")
-	flag.StringVar(&cfg.db.maxIdleTime, "db-max-idle-time", "15m", "PostgreSQL max connection idle time")
+	flag.Func("cors-trusted-origins", "Trusted CORS origins (space separated)", func(val string) error {
+		cfg.cors.trustedOrigins = strings.Fields(val)
+		return nil
+	})
+	flag.BoolVar(&cfg.cors.allowCredentials, "cors-allow-credentials", cfg.cors.allowCredentials, "Allow credentialed CORS requests (cookies, HTTP auth)")
+	flag.Func("cors-allow-headers", "Request headers allowed in CORS requests (comma separated, default Authorization,Content-Type)", func(val string) error {
+		cfg.cors.allowHeaders = splitList(val)
+		return nil
+	})
+	flag.Func("cors-expose-headers", "Response headers exposed to CORS requests (comma separated)", func(val string) error {
+		cfg.cors.exposeHeaders = splitList(val)
+		return nil
+	})
+	flag.DurationVar(&cfg.cors.maxAge, "cors-max-age", cfg.cors.maxAge, "How long browsers may cache CORS preflight responses (0 means not sent)")
+
+	// Start from the default security headers and let each -security-header flag
+	// override one of them, or remove it when given an empty value.
+	flag.Func("security-header", "Set a security header as Name=Value, or remove it with Name= (may be repeated)", func(val string) error {
+		return setSecurityHeader(cfg.securityHeaders, val)
+	})
+
+	flag.IntVar(&cfg.url.maxLength, "url-max-length", cfg.url.maxLength, "Maximum length in bytes of a request URL")
+	flag.IntVar(&cfg.url.maxQueryParams, "url-max-query-params", cfg.url.maxQueryParams, "Maximum number of query string parameters in a request URL")
+
+	flag.Int64Var(&cfg.body.maxBytes, "body-max-bytes", cfg.body.maxBytes, "Maximum size in bytes of a request body")
+	flag.IntVar(&cfg.body.maxFieldBytes, "body-max-field-bytes", cfg.body.maxFieldBytes, "Maximum size in bytes of a single JSON string value in a request body")
+	flag.IntVar(&cfg.body.maxDepth, "body-max-depth", cfg.body.maxDepth, "Maximum nesting depth of objects and arrays in a JSON request body")
 
 	flag.Parse()
 
-	logger := log.New(os.Stdout, "", log.Ldate|log.Ltime)
+	logger, logFile, err := newLogger(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if logFile != nil {
+		defer logFile.Close()
+	}
+
+	if !validator.PermittedValue(cfg.validationStatus, http.StatusBadRequest, http.StatusUnprocessableEntity) {
+		logger.Fatalf("invalid -validation-status %d: must be 400 or 422", cfg.validationStatus)
+	}
+
+	if !validator.PermittedValue(cfg.canonicalScheme, "http", "https") {
+		logger.Fatalf("invalid -canonical-scheme %q: must be http or https", cfg.canonicalScheme)
+	}
+
+	if cfg.tracing.sampleRatio < 0 || cfg.tracing.sampleRatio > 1 {
+		logger.Fatalf("invalid -tracing-sample-ratio %g: must be between 0 and 1", cfg.tracing.sampleRatio)
+	}
+
+	if !validator.PermittedValue(cfg.jsonOutputCase, jsonCaseSnake, jsonCaseCamel) {
+		logger.Fatalf("invalid -json-output-case %q: must be snake or camel", cfg.jsonOutputCase)
+	}
+
+	// Trusting every origin while allowing credentials would let any site make
+	// authenticated requests on a user's behalf.
+	if cfg.cors.allowCredentials && validator.PermittedValue("*", cfg.cors.trustedOrigins...) {
+		logger.Fatal("-cors-allow-credentials cannot be combined with a \"*\" trusted origin")
+	}
+
+	if cfg.exposePanicDetail && cfg.env == "production" {
+		logger.Fatal("-expose-panic-detail cannot be used in production")
+	}
+
+	err = data.SetTimeFormat(cfg.timeFormat)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	err = data.SetEmptyArrayBehavior(cfg.emptyArrayBehavior)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	err = data.ValidateGenreLimits(cfg.genres.min, cfg.genres.max)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	err = data.LoadTitleDenyList(cfg.titleDenyList)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	// Load feature flags from FEATURE_* environment variables, then enable anything
+	// listed in the -features flag on top.
+	features := flags.New()
+	err = features.LoadEnv(os.Environ())
+	if err != nil {
+		logger.Fatal(err)
+	}
+	for _, name := range splitList(cfg.features) {
+		features.Set(name, true)
+	}
+
+	translator, err := i18n.New()
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	// In preflight mode, run the startup checks and exit with their result instead
+	// of starting the server.
+	if cfg.preflight {
+		if !preflight(cfg, os.Stdout) {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	db, err := openDB(cfg)
 	if err != nil {
 		logger.Fatal(err)
@@ -57,22 +304,66 @@ func main() {
 
 	logger.Printf("database connection pool established")
 
+	// Refuse to start against a database that hasn't been migrated to the schema
+	// this build expects, rather than failing confusingly at runtime.
+	if !cfg.skipSchemaCheck {
+		err = checkSchemaVersion(db)
+		if err != nil {
+			logger.Fatal(err)
+		}
+	}
+
 	app := &application{
-		config: cfg,
-		logger: logger,
+		config:     cfg,
+		logger:     logger,
+		db:         db,
+		features:   features,
+		translator: translator,
+		limiter:    newIPRateLimiter(cfg.limiter.rps, cfg.limiter.burst),
+	}
+
+	app.readOnly.Store(cfg.readOnly)
+
+	if cfg.tracing.endpoint != "" {
+		app.tracerProvider, err = newTracerProvider(cfg.tracing.endpoint, cfg.tracing.sampleRatio)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		logger.Printf("exporting traces to %s", cfg.tracing.endpoint)
 	}
 
-	srv := &http.Server{
-		Addr:         fmt.Sprintf(":%d", cfg.port),
-		Handler:      app.routes(),
-		IdleTimeout:  time.Minute,
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 10 * time.Second,
+	err = app.serve()
+
+	// Flush any spans still waiting to be exported before we exit.
+	if app.tracerProvider != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := app.tracerProvider.Shutdown(ctx); err != nil {
+			logger.Printf("shutting down tracing: %s", err)
+		}
+	}
+
+	if err != nil {
+		logger.Fatal(err)
 	}
+}
 
-	logger.Printf("starting %s server on %s", cfg.env, srv.Addr)
-	err = srv.ListenAndServe()
-	logger.Fatal(err)
+// setSecurityHeader applies a -security-header value of the form Name=Value to
+// headers, removing the header instead when the value is empty.
+func setSecurityHeader(headers map[string]string, val string) error {
+	name, value, found := strings.Cut(val, "=")
+	if !found || strings.TrimSpace(name) == "" {
+		return errors.New("must be in the form Name=Value")
+	}
+
+	name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+	if value == "" {
+		delete(headers, name)
+		return nil
+	}
+	headers[name] = value
+	return nil
 }
 
 // The openDB() function returns a sql.DB connection pool.
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// trackInFlight records each request in app.inFlight for as long as it is being
+// handled.
+func (app *application) trackInFlight(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := app.inFlight.add(r.Method + " " + r.URL.Path)
+		defer app.inFlight.done(id)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// recoverPanic recovers from a panic in any later handler, logs it with a stack
+// trace and sends the client a 500 Internal Server Error response.
+func (app *application) recoverPanic(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if err := recover(); err != nil {
+				// http.ErrAbortHandler is used deliberately to abort a response, so let
+				// the server handle it as usual.
+				if err == http.ErrAbortHandler {
+					panic(err)
+				}
+
+				// Setting this header makes Go's HTTP server close the connection once
+				// the response has been sent.
+				w.Header().Set("Connection", "close")
+				app.panicResponse(w, r, err, debug.Stack())
+			}
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// redirectToCanonicalHost sends a 308 Permanent Redirect to the same URL on the
+// canonical host when one is configured and the request was made to a different
+// Host. Unlike 301, a 308 makes clients repeat the same method and body. Hosts are
+// compared without their ports, and the redirect uses the configured canonical
+// scheme, since behind a TLS-terminating load balancer the request itself always
+// arrives over plain HTTP. The healthcheck and readiness probe are exempt because
+// load balancers and orchestrators probe them by IP address.
+func (app *application) redirectToCanonicalHost(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		canonical := app.config.canonicalHost
+
+		if canonical != "" && !strings.EqualFold(hostname(r.Host), hostname(canonical)) && !isProbePath(r.URL.Path) {
+			target := app.config.canonicalScheme + "://" + canonical + r.URL.RequestURI()
+			http.Redirect(w, r, target, http.StatusPermanentRedirect)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// hostname returns host without any port.
+func hostname(host string) string {
+	if name, _, err := net.SplitHostPort(host); err == nil {
+		return name
+	}
+	return host
+}
+
+// isProbePath reports whether path is one of the health or readiness probe endpoints.
+func isProbePath(path string) bool {
+	return path == "/v1/healthcheck" || path == "/v1/readyz"
+}
+
+// readOnlyExemptPaths lists the endpoints that accept POST requests without
+// modifying any data, so they stay available in read-only mode.
+var readOnlyExemptPaths = map[string]bool{
+	"/v1/passwords/strength": true,
+}
+
+// rejectWritesWhenReadOnly refuses any request that could modify data while the
+// application is in read-only mode. GET, HEAD and OPTIONS requests, and requests to
+// readOnlyExemptPaths, are unaffected.
+func (app *application) rejectWritesWhenReadOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if app.readOnly.Load() && !readOnlyExemptPaths[r.URL.Path] {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+			default:
+				app.readOnlyResponse(w, r)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// enableCORS adds CORS headers for requests from trusted origins, and answers CORS
+// preflight requests directly. The Access-Control-Allow-Origin header always
+// echoes the exact request origin and is never "*", because browsers refuse a
+// wildcard on requests made with credentials.
+func (app *application) enableCORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The response depends on these request headers, so caches must key on them.
+		w.Header().Add("Vary", "Origin")
+		w.Header().Add("Vary", "Access-Control-Request-Method")
+
+		origin := r.Header.Get("Origin")
+		if trusted, listed := app.trustedOrigin(origin); origin != "" && trusted {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+
+			// main() refuses "*" combined with credentials, but don't rely on it:
+			// an origin that only matched the wildcard never gets credentials.
+			if app.config.cors.allowCredentials && listed {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+			if len(app.config.cors.exposeHeaders) > 0 {
+				w.Header().Set("Access-Control-Expose-Headers", strings.Join(app.config.cors.exposeHeaders, ", "))
+			}
+
+			// A preflight request is an OPTIONS request carrying an
+			// Access-Control-Request-Method header.
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+				w.Header().Set("Access-Control-Allow-Headers", strings.Join(app.config.cors.allowHeaders, ", "))
+				if maxAge := app.config.cors.maxAge; maxAge > 0 {
+					w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(maxAge.Seconds())))
+				}
+
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// trustedOrigin reports whether origin is trusted, and whether that is because it
+// is listed by name rather than only through a "*" entry, which trusts every origin.
+func (app *application) trustedOrigin(origin string) (trusted, listed bool) {
+	for _, entry := range app.config.cors.trustedOrigins {
+		switch entry {
+		case origin:
+			return true, true
+		case "*":
+			trusted = true
+		}
+	}
+	return trusted, false
+}
+
+// secureHeaders sets the configured security headers on every response. It should
+// wrap the router so that the headers are present even on error responses.
+func (app *application) secureHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for key, value := range app.config.securityHeaders {
+			w.Header().Set(key, value)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// limitURL rejects requests with pathologically long URLs or too many query
+// parameters before they reach the router and any query-string parsing.
+func (app *application) limitURL(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.RequestURI) > app.config.url.maxLength {
+			app.uriTooLongResponse(w, r)
+			return
+		}
+
+		// Count the parameters without parsing the query, since parsing is the work
+		// we are trying to protect.
+		if rawQuery := r.URL.RawQuery; rawQuery != "" {
+			if strings.Count(rawQuery, "&")+1 > app.config.url.maxQueryParams {
+				message := fmt.Sprintf("query string cannot contain more than %d parameters", app.config.url.maxQueryParams)
+				app.errorResponse(w, r, http.StatusBadRequest, message)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// cacheControl wraps a handler and sets the given Cache-Control policy on its
+// responses. If the policy contains a max-age directive, a matching Expires header is
+// also set for older caches that don't understand Cache-Control.
+func (app *application) cacheControl(policy string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", policy)
+
+		if maxAge, ok := parseMaxAge(policy); ok {
+			expires := time.Now().Add(maxAge).UTC().Format(http.TimeFormat)
+			w.Header().Set("Expires", expires)
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
+
+// parseMaxAge extracts the max-age directive from a Cache-Control policy.
+func parseMaxAge(policy string) (time.Duration, bool) {
+	for _, directive := range strings.Split(policy, ",") {
+		name, value, found := strings.Cut(strings.TrimSpace(directive), "=")
+		if !found || !strings.EqualFold(name, "max-age") {
+			continue
+		}
+
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	return 0, false
+}
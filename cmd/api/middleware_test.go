@@ -0,0 +1,337 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEnableCORS(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	tests := []struct {
+		name           string
+		trustedOrigins []string
+		origin         string
+		preflight      bool
+		wantCode       int
+		wantHeaders    map[string]string
+	}{
+		{
+			name:           "Trusted origin",
+			trustedOrigins: []string{"https://app.example.com"},
+			origin:         "https://app.example.com",
+			wantCode:       http.StatusTeapot,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      "https://app.example.com",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Expose-Headers":    "ETag, Warning",
+			},
+		},
+		{
+			name:           "Wildcard origin gets no credentials",
+			trustedOrigins: []string{"*"},
+			origin:         "https://other.example.com",
+			wantCode:       http.StatusTeapot,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      "https://other.example.com",
+				"Access-Control-Allow-Credentials": "",
+			},
+		},
+		{
+			name:           "Listed origin alongside a wildcard",
+			trustedOrigins: []string{"*", "https://app.example.com"},
+			origin:         "https://app.example.com",
+			wantCode:       http.StatusTeapot,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      "https://app.example.com",
+				"Access-Control-Allow-Credentials": "true",
+			},
+		},
+		{
+			name:           "Untrusted origin",
+			trustedOrigins: []string{"https://app.example.com"},
+			origin:         "https://evil.example.com",
+			wantCode:       http.StatusTeapot,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      "",
+				"Access-Control-Allow-Credentials": "",
+			},
+		},
+		{
+			name:           "Preflight",
+			trustedOrigins: []string{"https://app.example.com"},
+			origin:         "https://app.example.com",
+			preflight:      true,
+			wantCode:       http.StatusOK,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      "https://app.example.com",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Allow-Methods":     "GET, POST, PUT, PATCH, DELETE, OPTIONS",
+				"Access-Control-Allow-Headers":     "Authorization, Content-Type",
+				"Access-Control-Max-Age":           "600",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+			app.config.cors.trustedOrigins = tt.trustedOrigins
+			app.config.cors.allowCredentials = true
+			app.config.cors.exposeHeaders = []string{"ETag", "Warning"}
+			app.config.cors.maxAge = 10 * time.Minute
+
+			method := http.MethodGet
+			if tt.preflight {
+				method = http.MethodOptions
+			}
+			req := httptest.NewRequest(method, "/v1/healthcheck", nil)
+			req.Header.Set("Origin", tt.origin)
+			if tt.preflight {
+				req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			}
+
+			rr := httptest.NewRecorder()
+			app.enableCORS(next).ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantCode {
+				t.Errorf("got status %d; want %d", rr.Code, tt.wantCode)
+			}
+			for key, want := range tt.wantHeaders {
+				if got := rr.Header().Get(key); got != want {
+					t.Errorf("got %s %q; want %q", key, got, want)
+				}
+			}
+			if rr.Header().Get("Access-Control-Allow-Origin") == "*" {
+				t.Error("got Access-Control-Allow-Origin: * on a credentialed response")
+			}
+		})
+	}
+}
+
+func TestRedirectToCanonicalHost(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	tests := []struct {
+		name         string
+		canonical    string
+		host         string
+		target       string
+		wantCode     int
+		wantLocation string
+	}{
+		{"Canonical host", "api.example.com", "api.example.com", "/v1/movies", http.StatusTeapot, ""},
+		{"Different case", "api.example.com", "API.Example.com", "/v1/movies", http.StatusTeapot, ""},
+		{"Canonical host with a port", "api.example.com", "api.example.com:4000", "/v1/movies", http.StatusTeapot, ""},
+		{"Other host", "api.example.com", "10.0.0.1:4000", "/v1/movie/1?format=yaml", http.StatusPermanentRedirect, "https://api.example.com/v1/movie/1?format=yaml"},
+		{"Canonical port kept", "api.example.com:8443", "old.example.com", "/", http.StatusPermanentRedirect, "https://api.example.com:8443/"},
+		{"Healthcheck exempt", "api.example.com", "10.0.0.1", "/v1/healthcheck", http.StatusTeapot, ""},
+		{"Readiness probe exempt", "api.example.com", "10.0.0.1", "/v1/readyz", http.StatusTeapot, ""},
+		{"Not configured", "", "10.0.0.1", "/v1/movies", http.StatusTeapot, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+			app.config.canonicalHost = tt.canonical
+
+			req := httptest.NewRequest(http.MethodPost, tt.target, nil)
+			req.Host = tt.host
+			rr := httptest.NewRecorder()
+			app.redirectToCanonicalHost(next).ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantCode {
+				t.Errorf("got status %d; want %d", rr.Code, tt.wantCode)
+			}
+			if got := rr.Header().Get("Location"); got != tt.wantLocation {
+				t.Errorf("got Location %q; want %q", got, tt.wantLocation)
+			}
+		})
+	}
+}
+
+func TestSecureHeaders(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+
+	tests := []struct {
+		name     string
+		method   string
+		urlPath  string
+		wantCode int
+	}{
+		{"Success", http.MethodGet, "/v1/healthcheck", http.StatusOK},
+		{"Not found", http.MethodGet, "/v1/nothing-here", http.StatusNotFound},
+		{"Method not allowed", http.MethodDelete, "/v1/healthcheck", http.StatusMethodNotAllowed},
+		{"URI too long", http.MethodGet, "/v1/healthcheck?q=" + strings.Repeat("a", app.config.url.maxLength), http.StatusRequestURITooLong},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, headers, _ := ts.do(t, tt.method, tt.urlPath, "", nil)
+			if code != tt.wantCode {
+				t.Fatalf("got status %d; want %d", code, tt.wantCode)
+			}
+
+			for name, want := range defaultConfig().securityHeaders {
+				if got := headers.Get(name); got != want {
+					t.Errorf("got %s %q; want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSetSecurityHeader(t *testing.T) {
+	app := newTestApplication(t)
+
+	for _, val := range []string{"X-Frame-Options=", "content-security-policy=default-src 'self'", "Permissions-Policy=camera=()"} {
+		err := setSecurityHeader(app.config.securityHeaders, val)
+		if err != nil {
+			t.Fatalf("setting %q: %s", val, err)
+		}
+	}
+	for _, val := range []string{"X-Frame-Options", "=DENY", " =DENY"} {
+		if err := setSecurityHeader(app.config.securityHeaders, val); err == nil {
+			t.Errorf("got no error setting %q; want one", val)
+		}
+	}
+
+	ts := newTestServer(t, app.routes())
+	_, headers, _ := ts.get(t, "/v1/nothing-here")
+
+	want := map[string]string{
+		"X-Frame-Options":         "",
+		"Content-Security-Policy": "default-src 'self'",
+		"Permissions-Policy":      "camera=()",
+		"X-Content-Type-Options":  "nosniff",
+	}
+	for name, value := range want {
+		if got := headers.Get(name); got != value {
+			t.Errorf("got %s %q; want %q", name, got, value)
+		}
+	}
+	if _, ok := headers["X-Frame-Options"]; ok {
+		t.Error("got an X-Frame-Options header; want it removed")
+	}
+}
+
+func TestLimitURL(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	const maxLength = 32
+	const maxQueryParams = 3
+
+	// padded returns a URL starting with prefix that is exactly length bytes long.
+	padded := func(prefix string, length int) string {
+		return prefix + strings.Repeat("a", length-len(prefix))
+	}
+
+	tests := []struct {
+		name     string
+		target   string
+		wantCode int
+	}{
+		{"Short", "/v1/movie/1", http.StatusTeapot},
+		{"At the length limit", padded("/v1/", maxLength), http.StatusTeapot},
+		{"Over the length limit", padded("/v1/", maxLength+1), http.StatusRequestURITooLong},
+		{"Long query string", padded("/v1/movie/1?q=", maxLength+1), http.StatusRequestURITooLong},
+		{"At the parameter limit", "/v1/movies?a=1&b=2&c=3", http.StatusTeapot},
+		{"Over the parameter limit", "/v1/movies?a=1&b=2&c=3&d=4", http.StatusBadRequest},
+		{"Empty parameters count", "/v1/movies?&&&", http.StatusBadRequest},
+		{"Empty query", "/v1/movies?", http.StatusTeapot},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+			app.config.url.maxLength = maxLength
+			app.config.url.maxQueryParams = maxQueryParams
+
+			rr := httptest.NewRecorder()
+			app.limitURL(next).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			if rr.Code != tt.wantCode {
+				t.Errorf("got status %d for %d byte URL; want %d", rr.Code, len(tt.target), tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestRecoverPanic(t *testing.T) {
+	panicking := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom: secret detail")
+	})
+
+	t.Run("Opaque", func(t *testing.T) {
+		app := newTestApplication(t)
+
+		rr := httptest.NewRecorder()
+		app.recoverPanic(panicking).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if rr.Code != http.StatusInternalServerError {
+			t.Errorf("got status %d; want %d", rr.Code, http.StatusInternalServerError)
+		}
+		if got := rr.Header().Get("Connection"); got != "close" {
+			t.Errorf("got Connection %q; want %q", got, "close")
+		}
+
+		var message string
+		decodeEnvelope(t, rr.Body.Bytes(), "error", &message)
+		if message != "server encountered a problem and could not process your request" {
+			t.Errorf("got error %q; want the generic message", message)
+		}
+		if strings.Contains(rr.Body.String(), "secret detail") || strings.Contains(rr.Body.String(), "goroutine") {
+			t.Errorf("response leaks panic detail: %s", rr.Body)
+		}
+	})
+
+	t.Run("Detail exposed", func(t *testing.T) {
+		app := newTestApplication(t)
+		app.config.exposePanicDetail = true
+
+		rr := httptest.NewRecorder()
+		app.recoverPanic(panicking).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if rr.Code != http.StatusInternalServerError {
+			t.Errorf("got status %d; want %d", rr.Code, http.StatusInternalServerError)
+		}
+
+		var detail struct {
+			Message string   `json:"message"`
+			Panic   string   `json:"panic"`
+			Stack   []string `json:"stack"`
+		}
+		decodeEnvelope(t, rr.Body.Bytes(), "error", &detail)
+		if detail.Panic != "boom: secret detail" {
+			t.Errorf("got panic %q; want %q", detail.Panic, "boom: secret detail")
+		}
+		if len(detail.Stack) == 0 || !strings.HasPrefix(detail.Stack[0], "goroutine") {
+			t.Errorf("got stack %q; want a goroutine stack trace", detail.Stack)
+		}
+	})
+
+	t.Run("Abort handler", func(t *testing.T) {
+		app := newTestApplication(t)
+		aborting := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic(http.ErrAbortHandler)
+		})
+
+		defer func() {
+			if got := recover(); got != http.ErrAbortHandler {
+				t.Errorf("got panic %v; want http.ErrAbortHandler", got)
+			}
+		}()
+
+		app.recoverPanic(aborting).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+}
@@ -1,9 +1,13 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"greenlight/internal/data"
 	"greenlight/internal/validator"
+	"math"
 	"net/http"
 	"time"
 )
@@ -19,7 +23,15 @@ func (app *application) createMovieHandler(w http.ResponseWriter, r *http.Reques
 	// Decode the request body into the input struct.
 	err := app.readJSON(w, r, &input)
 	if err != nil {
-		app.badRequestResponse(w, r, err)
+		// A runtime too long to store is reported like any other invalid runtime in
+		// ValidateMovie().
+		if errors.Is(err, data.ErrRuntimeOutOfRange) {
+			app.failedValidationResponse(w, r, map[string]validator.Message{
+				"runtime": {Template: "must not be more than %d minutes", Args: []any{math.MaxInt32}},
+			})
+			return
+		}
+		app.readJSONErrorResponse(w, r, err)
 		return
 	}
 
@@ -33,8 +45,8 @@ func (app *application) createMovieHandler(w http.ResponseWriter, r *http.Reques
 
 	v := validator.New()
 
-	if data.ValidateMovie(v, movie); !v.Valid() {
-		app.failedValidationResponse(w, r, v.Errors)
+	if data.ValidateMovie(v, movie, app.config.genres.min, app.config.genres.max); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Messages)
 		return
 	}
 	fmt.Fprintf(w, "%+v\n", input)
@@ -48,21 +60,142 @@ func (app *application) showMovieHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Create a new instance of the Movie struct, containing the ID we extracted from
-	// the URL and some dummy data.
-	movie := data.Movie{
+	movie := dummyMovie(id)
+
+	// The representation depends on the Accept header, so make sure caches key on it.
+	w.Header().Add("Vary", "Accept")
+
+	rep, err := app.negotiate(r)
+	if err != nil {
+		app.notAcceptableResponse(w, r)
+		return
+	}
+
+	etag := app.movieETag(&movie, rep)
+	w.Header().Set("ETag", etag)
+
+	if notModified(w, r, etag) {
+		return
+	}
+
+	env := envelope{"movie": movie}
+	if rep.mediaType == jsonAPIMediaType {
+		resource, err := movieResource(&movie)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		env = envelope{"data": resource}
+	}
+
+	err = app.writeRepresentation(w, http.StatusOK, rep, env, nil)
+	if err != nil {
+		app.logger.Print(err)
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// exportMovieHandler sends a movie as a file to download. The format is chosen by
+// ?format=json or ?format=yaml if given, and otherwise negotiated from the Accept
+// header like any other response. Object keys are sorted at every level so that two
+// exports can be meaningfully diffed.
+func (app *application) exportMovieHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil || id < 1 {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var rep representation
+	if format := r.URL.Query().Get("format"); format != "" {
+		v := validator.New()
+		if v.Check(validator.PermittedValue(format, "json", "yaml"), "format", "must be json or yaml"); !v.Valid() {
+			app.failedValidationResponse(w, r, v.Messages)
+			return
+		}
+
+		for _, candidate := range app.representations() {
+			if candidate.name == format {
+				rep = candidate
+			}
+		}
+	} else {
+		w.Header().Add("Vary", "Accept")
+
+		rep, err = app.negotiate(r)
+		if err != nil {
+			app.notAcceptableResponse(w, r)
+			return
+		}
+	}
+
+	movie := dummyMovie(id)
+
+	env := envelope{}
+	if rep.mediaType == jsonAPIMediaType {
+		resource, err := movieResource(&movie)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		env["data"] = resource
+	} else {
+		// Go encodes maps with their keys sorted, so round-tripping the movie through
+		// a map sorts its fields.
+		js, err := json.Marshal(movie)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		var fields map[string]any
+		dec := json.NewDecoder(bytes.NewReader(js))
+		dec.UseNumber()
+		err = dec.Decode(&fields)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		env["movie"] = fields
+	}
+
+	extension := rep.name
+	if rep.mediaType == jsonAPIMediaType {
+		extension = "json"
+	}
+
+	headers := make(http.Header)
+	headers.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="movie-%d.%s"`, id, extension))
+
+	err = app.writeRepresentation(w, http.StatusOK, rep, env, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// dummyMovie returns a movie with the given ID and some dummy data, until movies
+// are stored in the database. Like a stored movie it is the same on every request,
+// so that its entity tag stays valid.
+func dummyMovie(id int64) data.Movie {
+	return data.Movie{
 		ID:        id,
-		CreatedAt: time.Now(),
+		CreatedAt: data.Timestamp(time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)),
 		Title:     "Casablanca",
 		Runtime:   102,
 		Genres:    []string{"drama", "romance", "war"},
 		Version:   1,
 	}
+}
 
-	// Encode the struct to JSON and send it as the HTTP response
-	err = app.writeJSON(w, http.StatusOK, envelope{"movie": movie}, nil)
-	if err != nil {
-		app.logger.Print(err)
-		app.serverErrorResponse(w, r, err)
+// movieETag returns the entity tag for a representation of a movie. It changes
+// whenever the movie's version does, so it is the single source of truth for both
+// cache validation on reads and concurrency checks on writes. Each representation
+// gets its own tag, because a strong tag must identify the exact bytes sent. Whether
+// the tag is weak or strong depends on the -etag-weak setting.
+func (app *application) movieETag(movie *data.Movie, rep representation) string {
+	etag := fmt.Sprintf(`"%d-%d-%s"`, movie.ID, movie.Version, rep.name)
+	if app.config.weakETags {
+		etag = "W/" + etag
 	}
+	return etag
 }
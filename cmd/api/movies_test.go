@@ -0,0 +1,240 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"greenlight/internal/validator"
+)
+
+func TestCreateMovieBodyTooLarge(t *testing.T) {
+	body := `{"title":"Moana","genres":["` + strings.Repeat("a", 200) + `"]}`
+
+	tests := []struct {
+		name          string
+		contentLength int64
+	}{
+		// Rejected from the declared Content-Length, before the body is read.
+		{"Declared length", int64(len(body))},
+		// No Content-Length, so MaxBytesReader stops reading at the limit.
+		{"Chunked", -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+			app.config.body.maxBytes = 100
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/movies", strings.NewReader(body))
+			req.ContentLength = tt.contentLength
+			rr := httptest.NewRecorder()
+			app.routes().ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusRequestEntityTooLarge {
+				t.Errorf("got status %d; want %d", rr.Code, http.StatusRequestEntityTooLarge)
+			}
+
+			var message string
+			decodeEnvelope(t, rr.Body.Bytes(), "error", &message)
+			if want := "body cannot be larger than 100 bytes"; message != want {
+				t.Errorf("got error %q; want %q", message, want)
+			}
+		})
+	}
+}
+
+func TestCreateMovieValidationStatus(t *testing.T) {
+	for _, status := range []int{http.StatusBadRequest, http.StatusUnprocessableEntity} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			app := newTestApplication(t)
+			app.config.validationStatus = status
+			ts := newTestServer(t, app.routes())
+
+			// Both a Validator failure and an oversized field must use the configured
+			// status.
+			bodies := []string{
+				`{"title":"","year":2000,"runtime":"100 mins","genres":["drama"]}`,
+				`{"title":"` + strings.Repeat("a", 5000) + `"}`,
+			}
+			for _, body := range bodies {
+				code, _, respBody := ts.do(t, http.MethodPost, "/v1/movies", body, nil)
+				if code != status {
+					t.Errorf("got status %d; want %d", code, status)
+				}
+
+				var errs map[string]string
+				decodeEnvelope(t, respBody, "error", &errs)
+				if _, ok := errs["title"]; !ok {
+					t.Errorf("got errors %v; want an error for title", errs)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateMovieRuntimeOutOfRange(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+
+	body := `{"title":"Moana","year":2016,"runtime":"2147483648 mins","genres":["animation"]}`
+	code, _, respBody := ts.do(t, http.MethodPost, "/v1/movies", body, nil)
+
+	if code != http.StatusUnprocessableEntity {
+		t.Errorf("got status %d; want %d", code, http.StatusUnprocessableEntity)
+	}
+
+	var errs map[string]string
+	decodeEnvelope(t, respBody, "error", &errs)
+	if want := "must not be more than 2147483647 minutes"; errs["runtime"] != want {
+		t.Errorf("got runtime error %q; want %q", errs["runtime"], want)
+	}
+}
+
+func TestShowMovieNegotiation(t *testing.T) {
+	tests := []struct {
+		name            string
+		jsonapi         bool
+		accept          string
+		wantCode        int
+		wantContentType string
+	}{
+		{"No Accept header", false, "", http.StatusOK, "application/json"},
+		{"XML", false, "application/xml", http.StatusOK, "application/xml"},
+		{"YAML", false, "application/yaml", http.StatusOK, "application/yaml"},
+		{"CSV", false, "text/csv", http.StatusOK, "text/csv"},
+		{"Most specific range wins", false, "text/*;q=0.5, application/json;q=0.4", http.StatusOK, "text/csv"},
+		{"Refused type", false, "application/json;q=0, */*", http.StatusOK, "application/xml"},
+		{"Unsupported", false, "image/png", http.StatusNotAcceptable, "application/json"},
+		{"JSON:API with the feature off", false, "application/vnd.api+json", http.StatusOK, "application/json"},
+		{"JSON:API", true, "application/vnd.api+json", http.StatusOK, jsonAPIMediaType},
+		{"JSON:API at lower quality", true, "application/json, application/vnd.api+json;q=0.1", http.StatusOK, "application/json"},
+		{"JSON:API refused", true, "application/vnd.api+json;q=0", http.StatusNotAcceptable, "application/json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+			app.features.Set("jsonapi", tt.jsonapi)
+			ts := newTestServer(t, app.routes())
+
+			headers := http.Header{}
+			if tt.accept != "" {
+				headers.Set("Accept", tt.accept)
+			}
+			code, respHeaders, body := ts.do(t, http.MethodGet, "/v1/movie/1", "", headers)
+
+			if code != tt.wantCode {
+				t.Errorf("got status %d; want %d", code, tt.wantCode)
+			}
+			if got := respHeaders.Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("got Content-Type %q; want %q", got, tt.wantContentType)
+			}
+			if got := respHeaders.Values("Vary"); !validator.PermittedValue("Accept", got...) {
+				t.Errorf("got Vary %q; want it to include Accept", got)
+			}
+			if code == http.StatusNotAcceptable && tt.jsonapi && !strings.Contains(string(body), jsonAPIMediaType) {
+				t.Errorf("got 406 body %s; want it to list %s", body, jsonAPIMediaType)
+			}
+		})
+	}
+}
+
+func TestShowMovieETagPerRepresentation(t *testing.T) {
+	app := newTestApplication(t)
+	app.features.Set("jsonapi", true)
+	ts := newTestServer(t, app.routes())
+
+	etags := make(map[string]string)
+	for _, mediaType := range []string{"application/json", "application/xml", "application/yaml", "text/csv", jsonAPIMediaType} {
+		_, headers, _ := ts.do(t, http.MethodGet, "/v1/movie/1", "", http.Header{"Accept": {mediaType}})
+
+		etag := headers.Get("ETag")
+		if other, seen := etags[etag]; seen {
+			t.Errorf("%s and %s share the entity tag %s", other, mediaType, etag)
+		}
+		etags[etag] = mediaType
+
+		// The tag validates its own representation, but not any other.
+		code, _, _ := ts.do(t, http.MethodGet, "/v1/movie/1", "", http.Header{"Accept": {mediaType}, "If-None-Match": {etag}})
+		if code != http.StatusNotModified {
+			t.Errorf("got status %d for %s with its own tag; want %d", code, mediaType, http.StatusNotModified)
+		}
+	}
+
+	_, headers, _ := ts.do(t, http.MethodGet, "/v1/movie/1", "", http.Header{"Accept": {"text/csv"}})
+	csvTag := headers.Get("ETag")
+	code, _, _ := ts.do(t, http.MethodGet, "/v1/movie/1", "", http.Header{"Accept": {"application/xml"}, "If-None-Match": {csvTag}})
+	if code != http.StatusOK {
+		t.Errorf("got status %d for XML with the CSV tag; want %d", code, http.StatusOK)
+	}
+}
+
+func TestExportMovie(t *testing.T) {
+	tests := []struct {
+		name            string
+		urlPath         string
+		accept          string
+		wantCode        int
+		wantContentType string
+		wantVary        bool
+		wantFilename    string
+		wantBodyPrefix  string
+	}{
+		{"JSON", "/v1/movie/7/export", "", http.StatusOK, "application/json", true, "movie-7.json", "{\n\t\"movie\": {\n\t\t\"created_at\""},
+		{"YAML", "/v1/movie/7/export?format=yaml", "", http.StatusOK, "application/yaml", false, "movie-7.yaml", "movie:\n  created_at:"},
+		{"Format overrides Accept", "/v1/movie/7/export?format=json", "application/xml", http.StatusOK, "application/json", false, "movie-7.json", "{"},
+		{"Negotiated XML", "/v1/movie/7/export", "application/xml", http.StatusOK, "application/xml", true, "movie-7.xml", "<?xml"},
+		{"Negotiated CSV", "/v1/movie/7/export", "text/csv", http.StatusOK, "text/csv", true, "movie-7.csv", "created_at,genres,id,"},
+		{"Not acceptable", "/v1/movie/7/export", "image/png", http.StatusNotAcceptable, "application/json", true, "", ""},
+		{"Unsupported format", "/v1/movie/7/export?format=xml", "", http.StatusUnprocessableEntity, "application/json", false, "", ""},
+		{"Invalid ID", "/v1/movie/0/export", "", http.StatusNotFound, "application/json", false, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+			ts := newTestServer(t, app.routes())
+
+			reqHeaders := http.Header{}
+			if tt.accept != "" {
+				reqHeaders.Set("Accept", tt.accept)
+			}
+
+			code, headers, body := ts.do(t, http.MethodGet, tt.urlPath, "", reqHeaders)
+
+			if code != tt.wantCode {
+				t.Fatalf("got status %d; want %d: %s", code, tt.wantCode, body)
+			}
+			if got := headers.Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("got Content-Type %q; want %q", got, tt.wantContentType)
+			}
+			if got := validator.PermittedValue("Accept", headers.Values("Vary")...); got != tt.wantVary {
+				t.Errorf("got Vary %q; want Accept in it: %t", headers.Values("Vary"), tt.wantVary)
+			}
+			if tt.wantFilename == "" {
+				return
+			}
+
+			want := `attachment; filename="` + tt.wantFilename + `"`
+			if got := headers.Get("Content-Disposition"); got != want {
+				t.Errorf("got Content-Disposition %q; want %q", got, want)
+			}
+			if !strings.HasPrefix(string(body), tt.wantBodyPrefix) {
+				t.Errorf("got body %q; want it to start with %q", body, tt.wantBodyPrefix)
+			}
+		})
+	}
+}
+
+func TestDummyMovieIsStable(t *testing.T) {
+	// A strong ETag promises the same bytes, so nothing in the movie may change
+	// between requests.
+	first, second := dummyMovie(1), dummyMovie(1)
+
+	if !time.Time(first.CreatedAt).Equal(time.Time(second.CreatedAt)) {
+		t.Errorf("got created_at %v and %v; want them equal", time.Time(first.CreatedAt), time.Time(second.CreatedAt))
+	}
+}
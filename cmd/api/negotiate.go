@@ -0,0 +1,468 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"greenlight/internal/validator"
+
+	"gopkg.in/yaml.v3"
+)
+
+// errNotAcceptable is returned by negotiate() when the client's Accept header doesn't
+// match any of the media types we can produce.
+var errNotAcceptable = errors.New("no acceptable media type")
+
+// A representation is a media type we can encode responses as. Its name tells
+// the representations apart in entity tags, and it is also chosen for any of its
+// aliases in the Accept header. Representations with no encode function are sent by
+// writeJSON(), or by writeJSONAPI() for JSON:API.
+type representation struct {
+	mediaType string
+	name      string
+	aliases   []string
+	encode    func(js []byte) ([]byte, error)
+}
+
+// representations lists the formats every response can be negotiated to. JSON comes
+// first, so that it wins whenever the client has no preference between types.
+var representations = []representation{
+	{mediaType: "application/json", name: "json"},
+	{mediaType: "application/xml", name: "xml", encode: encodeXML},
+	{mediaType: "application/yaml", name: "yaml", encode: encodeYAML},
+	{mediaType: "text/csv", name: "csv", encode: encodeCSV},
+}
+
+// representations returns the representations available for this application. With
+// the jsonapi feature enabled JSON:API can be negotiated too; without it, a request
+// for JSON:API gets the plain JSON envelope, exactly as before the feature existed.
+func (app *application) representations() []representation {
+	reps := append([]representation(nil), representations...)
+
+	if app.feature("jsonapi") {
+		return append(reps, representation{mediaType: jsonAPIMediaType, name: "jsonapi"})
+	}
+
+	reps[0].aliases = []string{jsonAPIMediaType}
+	return reps
+}
+
+// negotiate chooses the representation the client's Accept header prefers, by
+// quality value. JSON is chosen if the header is missing. If nothing acceptable is
+// supported errNotAcceptable is returned. Callers should set "Vary: Accept" before
+// calling it.
+func (app *application) negotiate(r *http.Request) (representation, error) {
+	rep, ok := chooseRepresentation(r.Header.Values("Accept"), app.representations())
+	if !ok {
+		return representation{}, errNotAcceptable
+	}
+	return rep, nil
+}
+
+// writeRepresentation sends data in the representation chosen by negotiate(). The
+// XML, YAML and CSV forms are all derived from the JSON encoding, so field names and
+// value formats are the same whichever format is chosen. For JSON:API, data must
+// already be a JSON:API document.
+func (app *application) writeRepresentation(w http.ResponseWriter, status int, rep representation, data envelope, headers http.Header) error {
+	switch {
+	case rep.mediaType == jsonAPIMediaType:
+		return app.writeJSONAPI(w, status, data, headers)
+	case rep.encode == nil:
+		return app.writeJSON(w, status, data, headers)
+	}
+
+	js, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	js, err = app.applyOutputCase(js)
+	if err != nil {
+		return err
+	}
+
+	body, err := rep.encode(js)
+	if err != nil {
+		return err
+	}
+
+	for key, value := range headers {
+		w.Header()[key] = value
+	}
+
+	app.addDeprecationWarnings(w, js)
+
+	w.Header().Set("Content-Type", rep.mediaType)
+	w.WriteHeader(status)
+	w.Write(body)
+
+	return nil
+}
+
+// chooseRepresentation picks the representation in reps with the highest quality
+// value in the Accept header values. Each media type takes its quality from the most
+// specific range that matches it, so "text/*;q=0.5, text/csv" prefers CSV. Ties go to
+// the earlier representation.
+func chooseRepresentation(accept []string, reps []representation) (representation, bool) {
+	type mediaRange struct {
+		mediaType string
+		q         float64
+	}
+
+	var ranges []mediaRange
+	for _, header := range accept {
+		for _, part := range strings.Split(header, ",") {
+			if strings.TrimSpace(part) == "" {
+				continue
+			}
+
+			mediaType, params, err := mime.ParseMediaType(part)
+			if err != nil {
+				continue
+			}
+
+			q := 1.0
+			if value, ok := params["q"]; ok {
+				q, err = strconv.ParseFloat(value, 64)
+				if err != nil {
+					continue
+				}
+			}
+			ranges = append(ranges, mediaRange{mediaType, q})
+		}
+	}
+
+	if len(ranges) == 0 {
+		return reps[0], true
+	}
+
+	var best representation
+	bestQ := 0.0
+	for _, rep := range reps {
+		major, _, _ := strings.Cut(rep.mediaType, "/")
+
+		// Specificity is 1 for */*, 2 for type/* and 3 for an exact match.
+		q, specificity := 0.0, 0
+		for _, mr := range ranges {
+			s := 0
+			switch {
+			case mr.mediaType == rep.mediaType || validator.PermittedValue(mr.mediaType, rep.aliases...):
+				s = 3
+			case mr.mediaType == major+"/*":
+				s = 2
+			case mr.mediaType == "*/*":
+				s = 1
+			}
+			if s > specificity {
+				q, specificity = mr.q, s
+			}
+		}
+
+		if q > bestQ {
+			best, bestQ = rep, q
+		}
+	}
+
+	return best, bestQ > 0
+}
+
+// decodeOrdered decodes the next JSON value from dec, keeping the order of object
+// keys. Objects are returned as orderedObject, arrays as []any, numbers as
+// json.Number and everything else as the usual Go types.
+func decodeOrdered(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		var object orderedObject
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			object = append(object, orderedMember{key.(string), value})
+		}
+		_, err = dec.Token()
+		return object, err
+
+	case json.Delim('['):
+		array := []any{}
+		for dec.More() {
+			value, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		_, err = dec.Token()
+		return array, err
+	}
+
+	return tok, nil
+}
+
+// An orderedObject is a JSON object that keeps its keys in their original order,
+// including when it is encoded back to JSON.
+type orderedObject []orderedMember
+
+type orderedMember struct {
+	key   string
+	value any
+}
+
+func (o orderedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+
+	for i, member := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		key, err := json.Marshal(member.key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(member.value)
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func parseOrdered(js []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(js))
+	dec.UseNumber()
+	return decodeOrdered(dec)
+}
+
+// scalarString formats a JSON scalar as text. Null is the empty string.
+func scalarString(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// encodeXML converts a JSON document to XML under a <response> root element. Each
+// object key becomes an element, and the elements of an array are repeated under the
+// array's key, as encoding/xml does for slices.
+func encodeXML(js []byte) ([]byte, error) {
+	value, err := parseOrdered(js)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "\t")
+
+	err = writeXMLElement(enc, "response", value)
+	if err != nil {
+		return nil, err
+	}
+
+	err = enc.Flush()
+	if err != nil {
+		return nil, err
+	}
+
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+func writeXMLElement(enc *xml.Encoder, name string, value any) error {
+	if array, ok := value.([]any); ok {
+		for _, element := range array {
+			err := writeXMLElement(enc, name, element)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	err := enc.EncodeToken(start)
+	if err != nil {
+		return err
+	}
+
+	if object, ok := value.(orderedObject); ok {
+		for _, member := range object {
+			err = writeXMLElement(enc, member.key, member.value)
+			if err != nil {
+				return err
+			}
+		}
+	} else if value != nil {
+		err = enc.EncodeToken(xml.CharData(scalarString(value)))
+		if err != nil {
+			return err
+		}
+	}
+
+	return enc.EncodeToken(start.End())
+}
+
+// encodeYAML converts a JSON document to YAML, keeping the key order and the
+// distinction between numbers and strings.
+func encodeYAML(js []byte) ([]byte, error) {
+	value, err := parseOrdered(js)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+
+	err = enc.Encode(yamlNode(value))
+	if err != nil {
+		return nil, err
+	}
+
+	err = enc.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func yamlNode(value any) *yaml.Node {
+	switch value := value.(type) {
+	case orderedObject:
+		node := &yaml.Node{Kind: yaml.MappingNode}
+		for _, member := range value {
+			node.Content = append(node.Content, yamlNode(member.key), yamlNode(member.value))
+		}
+		return node
+	case []any:
+		node := &yaml.Node{Kind: yaml.SequenceNode}
+		for _, element := range value {
+			node.Content = append(node.Content, yamlNode(element))
+		}
+		return node
+	case nil:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(value)}
+	case json.Number:
+		tag := "!!float"
+		if _, err := value.Int64(); err == nil {
+			tag = "!!int"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value.String()}
+	default:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: scalarString(value)}
+	}
+}
+
+// encodeCSV converts a JSON document to CSV. The envelope must hold a single object,
+// or an array of objects, which become the rows. The header row lists every key in
+// the order first seen. Arrays of scalars are joined with commas within their cell;
+// nested objects can't be represented and are an error.
+func encodeCSV(js []byte) ([]byte, error) {
+	value, err := parseOrdered(js)
+	if err != nil {
+		return nil, err
+	}
+
+	envelope, ok := value.(orderedObject)
+	if !ok || len(envelope) != 1 {
+		return nil, errors.New("csv: response must contain a single top-level member")
+	}
+
+	var records []orderedObject
+	switch value := envelope[0].value.(type) {
+	case orderedObject:
+		records = append(records, value)
+	case []any:
+		for _, element := range value {
+			record, ok := element.(orderedObject)
+			if !ok {
+				return nil, errors.New("csv: array elements must be objects")
+			}
+			records = append(records, record)
+		}
+	default:
+		return nil, errors.New("csv: response must contain an object or an array of objects")
+	}
+
+	var columns []string
+	index := make(map[string]int)
+	for _, record := range records {
+		for _, member := range record {
+			if _, ok := index[member.key]; !ok {
+				index[member.key] = len(columns)
+				columns = append(columns, member.key)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write(columns)
+
+	for _, record := range records {
+		row := make([]string, len(columns))
+		for _, member := range record {
+			row[index[member.key]], err = csvCell(member.value)
+			if err != nil {
+				return nil, err
+			}
+		}
+		w.Write(row)
+	}
+
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+func csvCell(value any) (string, error) {
+	switch value := value.(type) {
+	case orderedObject:
+		return "", errors.New("csv: nested objects are not supported")
+	case []any:
+		cells := make([]string, len(value))
+		for i, element := range value {
+			cell, err := csvCell(element)
+			if err != nil {
+				return "", err
+			}
+			cells[i] = cell
+		}
+		return strings.Join(cells, ","), nil
+	default:
+		return scalarString(value), nil
+	}
+}
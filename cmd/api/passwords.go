@@ -0,0 +1,40 @@
+package main
+
+import (
+	"net/http"
+
+	"greenlight/internal/data"
+	"greenlight/internal/validator"
+)
+
+// passwordStrengthHandler scores a candidate password and lists the password rules
+// it fails, without creating or storing anything. The password must never be
+// logged, so errors from here are only ever reported back to the client.
+func (app *application) passwordStrengthHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Password string `json:"password"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.readJSONErrorResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	data.ValidatePasswordPlaintext(v, input.Password)
+
+	lang := app.translator.Match(r.Header.Get("Accept-Language"))
+	w.Header().Add("Vary", "Accept-Language")
+	w.Header().Set("Content-Language", lang.String())
+
+	env := envelope{
+		"score":        data.PasswordStrength(input.Password),
+		"failed_rules": app.translator.TranslateAll(lang, v.Messages),
+	}
+
+	err = app.writeJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
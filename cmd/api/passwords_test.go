@@ -0,0 +1,123 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestPasswordStrength(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		language    string
+		wantCode    int
+		wantScore   int
+		wantFailed  map[string]string
+		wantErrorIn string
+	}{
+		{
+			name:       "Strong",
+			body:       `{"password":"correct Horse 9 battery"}`,
+			wantCode:   http.StatusOK,
+			wantScore:  4,
+			wantFailed: map[string]string{},
+		},
+		{
+			name:       "Common",
+			body:       `{"password":"Password"}`,
+			wantCode:   http.StatusOK,
+			wantFailed: map[string]string{"common": "must not be a commonly used password"},
+		},
+		{
+			name:       "Translated",
+			body:       `{"password":"short"}`,
+			language:   "de",
+			wantCode:   http.StatusOK,
+			wantFailed: map[string]string{"length": "muss mindestens 8 Bytes lang sein"},
+		},
+		{
+			name:        "Oversized field",
+			body:        `{"password":"` + strings.Repeat("a", 5000) + `"}`,
+			wantCode:    http.StatusUnprocessableEntity,
+			wantErrorIn: "password",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+			ts := newTestServer(t, app.routes())
+
+			headers := http.Header{}
+			if tt.language != "" {
+				headers.Set("Accept-Language", tt.language)
+			}
+
+			code, _, body := ts.do(t, http.MethodPost, "/v1/passwords/strength", tt.body, headers)
+			if code != tt.wantCode {
+				t.Fatalf("got status %d; want %d: %s", code, tt.wantCode, body)
+			}
+
+			if tt.wantErrorIn != "" {
+				var errs map[string]string
+				decodeEnvelope(t, body, "error", &errs)
+				if _, ok := errs[tt.wantErrorIn]; !ok {
+					t.Errorf("got errors %v; want an error for %q", errs, tt.wantErrorIn)
+				}
+				return
+			}
+
+			var score int
+			var failed map[string]string
+			decodeEnvelope(t, body, "score", &score)
+			decodeEnvelope(t, body, "failed_rules", &failed)
+
+			if score != tt.wantScore {
+				t.Errorf("got score %d; want %d", score, tt.wantScore)
+			}
+			if len(failed) != len(tt.wantFailed) {
+				t.Errorf("got failed rules %v; want %v", failed, tt.wantFailed)
+			}
+			for rule, message := range tt.wantFailed {
+				if failed[rule] != message {
+					t.Errorf("got %q for rule %q; want %q", failed[rule], rule, message)
+				}
+			}
+		})
+	}
+}
+
+func TestPasswordStrengthReadOnly(t *testing.T) {
+	app := newTestApplication(t)
+	app.readOnly.Store(true)
+	ts := newTestServer(t, app.routes())
+
+	code, _, _ := ts.do(t, http.MethodPost, "/v1/passwords/strength", `{"password":"correct Horse 9 battery"}`, nil)
+	if code != http.StatusOK {
+		t.Errorf("got status %d; want %d", code, http.StatusOK)
+	}
+
+	code, _, _ = ts.do(t, http.MethodPost, "/v1/movies", `{}`, nil)
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("got status %d for a write; want %d", code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestPasswordStrengthRateLimit(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+
+	// The default burst is allowed straight away, and the next request is refused.
+	for i := 0; i < app.config.limiter.burst; i++ {
+		code, _, _ := ts.do(t, http.MethodPost, "/v1/passwords/strength", `{"password":"correct Horse 9 battery"}`, nil)
+		if code != http.StatusOK {
+			t.Fatalf("got status %d for request %d; want %d", code, i+1, http.StatusOK)
+		}
+	}
+
+	code, _, _ := ts.do(t, http.MethodPost, "/v1/passwords/strength", `{"password":"correct Horse 9 battery"}`, nil)
+	if code != http.StatusTooManyRequests {
+		t.Errorf("got status %d; want %d", code, http.StatusTooManyRequests)
+	}
+}
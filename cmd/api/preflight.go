@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"greenlight/migrations"
+)
+
+// errSkipped marks a preflight check that could not run because an earlier check it
+// depends on failed.
+var errSkipped = errors.New("skipped")
+
+// The preflight() function verifies that the configuration is usable without
+// starting the HTTP server. It prints a pass/fail line for each check to out and
+// returns true only if every check passed.
+func preflight(cfg config, out io.Writer) bool {
+	var db *sql.DB
+
+	checks := []struct {
+		name string
+		run  func() error
+	}{
+		{"database connection", func() error {
+			var err error
+			db, err = openDB(cfg)
+			return err
+		}},
+		{"database query", func() error {
+			if db == nil {
+				return errSkipped
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			var one int
+			return db.QueryRowContext(ctx, "SELECT 1").Scan(&one)
+		}},
+		{"migration version", func() error {
+			if db == nil {
+				return errSkipped
+			}
+
+			return checkSchemaVersion(db)
+		}},
+	}
+
+	ok := true
+	for _, check := range checks {
+		err := check.run()
+		switch {
+		case err == nil:
+			fmt.Fprintf(out, "PASS  %s\n", check.name)
+		case errors.Is(err, errSkipped):
+			fmt.Fprintf(out, "SKIP  %s\n", check.name)
+			ok = false
+		default:
+			fmt.Fprintf(out, "FAIL  %s: %v\n", check.name, err)
+			ok = false
+		}
+	}
+
+	if db != nil {
+		db.Close()
+	}
+
+	return ok
+}
+
+// The checkSchemaVersion() function returns an error unless the database schema is
+// at exactly the migration version embedded in this binary, and not dirty.
+func checkSchemaVersion(db *sql.DB) error {
+	expected, err := migrations.ExpectedVersion()
+	if err != nil {
+		return err
+	}
+
+	version, dirty, err := migrationVersion(db)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case dirty:
+		return fmt.Errorf("schema version %d is dirty", version)
+	case version != expected:
+		return fmt.Errorf("schema version is %d but this build expects %d", version, expected)
+	}
+	return nil
+}
+
+// The migrationVersion() function returns the schema version recorded by the
+// migrate tool, and whether the last migration was left half-applied.
+func migrationVersion(db *sql.DB) (int64, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var version int64
+	var dirty bool
+
+	err := db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, false, errors.New("no migrations have been applied")
+		}
+		return 0, false, err
+	}
+
+	return version, dirty, nil
+}
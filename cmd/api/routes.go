@@ -6,7 +6,7 @@ import (
 	"github.com/julienschmidt/httprouter"
 )
 
-func (app *application) routes() *httprouter.Router {
+func (app *application) routes() http.Handler {
 	router := httprouter.New()
 
 	// Convert the notFoundResponse() helper to a http.Handler using the
@@ -18,9 +18,26 @@ func (app *application) routes() *httprouter.Router {
 	// it as the custom error handler for 405 Method Not Allowed responses.
 	router.MethodNotAllowed = http.HandlerFunc(app.methodNotAllowedResponse)
 
-	router.HandlerFunc(http.MethodGet, "/v1/healthcheck", app.healtcheckHandler)
-	router.HandlerFunc(http.MethodPost, "/v1/movies", app.createMovieHandler)
-	router.HandlerFunc(http.MethodGet, "/v1/movie/:id", app.showMovieHandler)
+	// Wrap each handler with the caching policy that should be sent to clients and
+	// any CDNs in front of us. Error responses always override this with no-store.
+	router.HandlerFunc(http.MethodGet, "/", app.cacheControl("public, max-age=3600", app.rootHandler))
+	router.HandlerFunc(http.MethodGet, "/favicon.ico", app.cacheControl("public, max-age=86400", app.faviconHandler))
+	router.HandlerFunc(http.MethodGet, "/debug/vars", app.cacheControl("no-store", app.debugVarsHandler))
 
-	return router
+	router.HandlerFunc(http.MethodGet, "/v1/healthcheck", app.cacheControl("no-store", app.healtcheckHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/readyz", app.cacheControl("no-store", app.readyzHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/movies", app.cacheControl("no-store", app.createMovieHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/passwords/strength", app.cacheControl("no-store", app.rateLimit(app.passwordStrengthHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/movie/:id", app.cacheControl("public, max-age=60", app.showMovieHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/movie/:id/export", app.cacheControl("public, max-age=60", app.exportMovieHandler))
+
+	handler := app.trackInFlight(app.recoverPanic(app.secureHeaders(app.enableCORS(app.limitURL(app.redirectToCanonicalHost(app.rejectWritesWhenReadOnly(router)))))))
+
+	// Only wrap the handler for tracing when it is enabled, so that it costs nothing
+	// otherwise.
+	if app.tracerProvider != nil {
+		handler = app.traceRequests(handler)
+	}
+
+	return handler
 }
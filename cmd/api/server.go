@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"greenlight/internal/data"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"golang.org/x/net/netutil"
+)
+
+// serve starts the HTTP server and blocks until it has shut down. On SIGINT or
+// SIGTERM the application is first marked as draining, so that the readiness probe
+// fails and load balancers stop routing to us, and then after the configured drain
+// delay the server is gracefully shut down.
+func (app *application) serve() error {
+	srv := &http.Server{
+		Addr:         fmt.Sprintf(":%d", app.config.port),
+		Handler:      app.routes(),
+		IdleTimeout:  time.Minute,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+
+	// With h2c enabled, the router is wrapped so that clients can speak HTTP/2 over
+	// plain TCP. Configuring the HTTP/2 server against srv means Shutdown() also
+	// sends GOAWAY to open HTTP/2 connections, not just those on HTTP/1.x.
+	if app.config.h2c {
+		h2s := &http2.Server{IdleTimeout: srv.IdleTimeout}
+		err := http2.ConfigureServer(srv, h2s)
+		if err != nil {
+			return err
+		}
+		srv.Handler = h2c.NewHandler(srv.Handler, h2s)
+	}
+
+	// Reload the configuration files on SIGHUP. A file that fails to load is
+	// logged and the previous settings are kept.
+	go func() {
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+
+		for range hup {
+			err := data.LoadTitleDenyList(app.config.titleDenyList)
+			if err != nil {
+				app.logger.Printf("reloading title deny-list: %s", err)
+				continue
+			}
+			app.logger.Printf("reloaded configuration")
+		}
+	}()
+
+	shutdownError := make(chan error)
+
+	go func() {
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+
+		s := <-quit
+		app.logger.Printf("caught signal %s", s)
+
+		app.draining.Store(true)
+		if delay := app.config.shutdownDrainDelay; delay > 0 {
+			app.logger.Printf("draining for %s before shutting down", delay)
+			time.Sleep(delay)
+		}
+
+		// Give in-flight requests up to 20 seconds to complete.
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		defer cancel()
+
+		app.logger.Printf("shutting down server")
+
+		// Report how many requests are still in flight every second until they have
+		// all finished.
+		stopReporting := make(chan struct{})
+		go func() {
+			ticker := time.NewTicker(time.Second)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ticker.C:
+					app.logger.Printf("waiting for %d in-flight requests", app.inFlight.count())
+				case <-stopReporting:
+					return
+				}
+			}
+		}()
+
+		err := srv.Shutdown(ctx)
+
+		// The h2c handler takes HTTP/2 connections over from srv, so Shutdown()
+		// doesn't wait for their requests. Wait for every tracked request instead,
+		// within the same deadline.
+		if err == nil {
+			err = app.inFlight.wait(ctx)
+		}
+		close(stopReporting)
+
+		// If we ran out of time, log what was still running to help diagnose stuck
+		// handlers.
+		if errors.Is(err, context.DeadlineExceeded) {
+			app.logger.Printf("shutdown timed out with %d requests in flight: %s",
+				app.inFlight.count(), strings.Join(app.inFlight.routes(), ", "))
+		}
+
+		shutdownError <- err
+	}()
+
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		return err
+	}
+
+	// Cap the number of simultaneous connections if configured. Connections beyond
+	// the limit wait in the kernel's accept backlog rather than using up file
+	// descriptors.
+	if max := app.config.maxConnections; max > 0 {
+		ln = netutil.LimitListener(ln, max)
+	}
+
+	app.logger.Printf("starting %s server on %s", app.config.env, srv.Addr)
+
+	// Calling Shutdown() makes Serve() return http.ErrServerClosed straight away, so
+	// anything else is a real error. Otherwise wait for Shutdown() to finish.
+	err = srv.Serve(ln)
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	err = <-shutdownError
+	if err != nil {
+		return err
+	}
+
+	app.logger.Printf("stopped server on %s", srv.Addr)
+	return nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"greenlight/internal/flags"
+	"greenlight/internal/i18n"
+)
+
+// newTestApplication returns an application with the default configuration,
+// logging to nowhere. Tests can adjust app.config before
+// building the routes.
+func newTestApplication(t *testing.T) *application {
+	t.Helper()
+
+	cfg := defaultConfig()
+
+	translator, err := i18n.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &application{
+		config:     cfg,
+		logger:     log.New(io.Discard, "", 0),
+		features:   flags.New(),
+		translator: translator,
+		limiter:    newIPRateLimiter(cfg.limiter.rps, cfg.limiter.burst),
+	}
+}
+
+// testServer is an httptest.Server running the application's real router.
+type testServer struct {
+	*httptest.Server
+}
+
+// newTestServer starts a test server for h, which is closed when the test ends.
+func newTestServer(t *testing.T, h http.Handler) *testServer {
+	t.Helper()
+
+	ts := httptest.NewServer(h)
+	t.Cleanup(ts.Close)
+
+	return &testServer{ts}
+}
+
+// do sends a request to the test server and returns the response status code,
+// headers and body. Any headers given are added to the request.
+func (ts *testServer) do(t *testing.T, method, urlPath, body string, headers http.Header) (int, http.Header, []byte) {
+	t.Helper()
+
+	req, err := http.NewRequest(method, ts.URL+urlPath, bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, values := range headers {
+		req.Header[key] = values
+	}
+
+	rs, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Body.Close()
+
+	respBody, err := io.ReadAll(rs.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return rs.StatusCode, rs.Header, respBody
+}
+
+// get is a shortcut for a GET request with no body or extra headers.
+func (ts *testServer) get(t *testing.T, urlPath string) (int, http.Header, []byte) {
+	t.Helper()
+	return ts.do(t, http.MethodGet, urlPath, "", nil)
+}
+
+// decodeEnvelope decodes the member called key of a JSON envelope response body
+// into dst.
+func decodeEnvelope(t *testing.T, body []byte, key string, dst any) {
+	t.Helper()
+
+	var env map[string]json.RawMessage
+	err := json.Unmarshal(body, &env)
+	if err != nil {
+		t.Fatalf("decoding envelope: %s: %q", err, body)
+	}
+
+	value, ok := env[key]
+	if !ok {
+		t.Fatalf("envelope has no %q member: %q", key, body)
+	}
+
+	err = json.Unmarshal(value, dst)
+	if err != nil {
+		t.Fatalf("decoding envelope member %q: %s", key, err)
+	}
+}
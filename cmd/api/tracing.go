@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// newTracerProvider returns an OpenTelemetry tracer provider that exports spans in
+// batches to the OTLP/HTTP collector at endpoint, such as http://localhost:4318.
+// New traces are sampled at the given ratio, while requests that arrive as part of
+// a trace follow the caller's sampling decision.
+func newTracerProvider(endpoint string, sampleRatio float64) (*sdktrace.TracerProvider, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("invalid tracing endpoint %q: must be an http or https URL", endpoint)
+	}
+
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(u.Host)}
+	if u.Scheme == "http" {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	if u.Path != "" && u.Path != "/" {
+		opts = append(opts, otlptracehttp.WithURLPath(u.Path))
+	}
+
+	// Creating the exporter doesn't connect to the collector, so this doesn't fail
+	// or block when the collector is unavailable.
+	exporter, err := otlptracehttp.New(context.Background(), opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", "greenlight"),
+			attribute.String("service.version", version),
+		)),
+	), nil
+}
+
+// untracedPaths are left out of tracing: browser probes of the API root and favicon,
+// and scrapes of /debug/vars, would otherwise add a span on every hit.
+var untracedPaths = map[string]bool{
+	"/":            true,
+	"/favicon.ico": true,
+	"/debug/vars":  true,
+}
+
+// traceRequests starts a server span for each request, continuing the trace from
+// an incoming W3C traceparent header if there is one.
+func (app *application) traceRequests(next http.Handler) http.Handler {
+	return otelhttp.NewHandler(next, "",
+		otelhttp.WithTracerProvider(app.tracerProvider),
+		otelhttp.WithPropagators(propagation.TraceContext{}),
+		otelhttp.WithFilter(func(r *http.Request) bool {
+			return !untracedPaths[r.URL.Path]
+		}),
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return "HTTP " + r.Method
+		}),
+	)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTraceRequests(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+
+	app := newTestApplication(t)
+	app.tracerProvider = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	ts := newTestServer(t, app.routes())
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	const parentID = "00f067aa0ba902b7"
+
+	headers := http.Header{}
+	headers.Set("traceparent", "00-"+traceID+"-"+parentID+"-01")
+
+	code, _, _ := ts.do(t, http.MethodGet, "/v1/healthcheck", "", headers)
+	if code != http.StatusOK {
+		t.Fatalf("got status %d; want %d", code, http.StatusOK)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans; want 1", len(spans))
+	}
+	span := spans[0]
+
+	if got := span.Name(); got != "HTTP GET" {
+		t.Errorf("got span name %q; want %q", got, "HTTP GET")
+	}
+	if got := span.SpanKind(); got != trace.SpanKindServer {
+		t.Errorf("got span kind %s; want %s", got, trace.SpanKindServer)
+	}
+	if got := span.SpanContext().TraceID().String(); got != traceID {
+		t.Errorf("got trace ID %s; want %s", got, traceID)
+	}
+	if got := span.Parent().SpanID().String(); got != parentID {
+		t.Errorf("got parent span ID %s; want %s", got, parentID)
+	}
+}
+
+func TestTraceRequestsSkipsProbes(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+
+	app := newTestApplication(t)
+	app.tracerProvider = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	ts := newTestServer(t, app.routes())
+
+	for _, urlPath := range []string{"/", "/favicon.ico", "/debug/vars"} {
+		ts.get(t, urlPath)
+	}
+	if spans := recorder.Ended(); len(spans) != 0 {
+		t.Errorf("got %d spans for probe paths; want none", len(spans))
+	}
+
+	ts.get(t, "/v1/movie/1")
+	if spans := recorder.Ended(); len(spans) != 1 {
+		t.Errorf("got %d spans; want 1", len(spans))
+	}
+}
+
+func TestNewTracerProviderEndpoint(t *testing.T) {
+	for _, endpoint := range []string{"localhost:4318", "ftp://collector", "http://"} {
+		_, err := newTracerProvider(endpoint, 1)
+		if err == nil {
+			t.Errorf("got no error for endpoint %q; want one", endpoint)
+		}
+	}
+
+	tp, err := newTracerProvider("http://localhost:4318/v1/traces", 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tp.Shutdown(context.Background())
+}
@@ -0,0 +1,101 @@
+package data
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"sync/atomic"
+	"unicode"
+)
+
+// denyList is a set of banned words and phrases for movie titles. Entries are
+// stored lower case, with the words of a phrase joined by single spaces, so that a
+// title can be checked with one map lookup per run of up to maxWords words.
+type denyList struct {
+	phrases  map[string]bool
+	maxWords int
+}
+
+// titleDenyList is swapped atomically so that it can be reloaded while requests are
+// being validated.
+var titleDenyList atomic.Pointer[denyList]
+
+// SetTitleDenyList replaces the words and phrases that ValidateMovie() rejects in
+// titles. Matching is case-insensitive and on whole words only, so banning "ass"
+// doesn't reject "The Glass Castle". An empty list disables the check.
+func SetTitleDenyList(entries []string) {
+	list := &denyList{phrases: make(map[string]bool)}
+
+	for _, entry := range entries {
+		words := titleWords(entry)
+		if len(words) == 0 {
+			continue
+		}
+
+		list.phrases[strings.Join(words, " ")] = true
+		if len(words) > list.maxWords {
+			list.maxWords = len(words)
+		}
+	}
+
+	titleDenyList.Store(list)
+}
+
+// LoadTitleDenyList reads the title deny-list from a file with one word or phrase per
+// line. Blank lines and lines starting with # are ignored. An empty path clears the
+// list. If the file can't be read the current list is left in place.
+func LoadTitleDenyList(path string) error {
+	if path == "" {
+		SetTitleDenyList(nil)
+		return nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var entries []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entries = append(entries, line)
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return err
+	}
+
+	SetTitleDenyList(entries)
+	return nil
+}
+
+// titleDenied reports whether title contains any word or phrase on the deny-list.
+func titleDenied(title string) bool {
+	list := titleDenyList.Load()
+	if list == nil || len(list.phrases) == 0 {
+		return false
+	}
+
+	words := titleWords(title)
+	for i := range words {
+		for n := 1; n <= list.maxWords && i+n <= len(words); n++ {
+			if list.phrases[strings.Join(words[i:i+n], " ")] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// titleWords splits s into lower case words of letters and digits.
+func titleWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,111 @@
+package data
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// setTitleDenyList sets the deny-list for the rest of a test.
+func setTitleDenyList(t *testing.T, entries []string) {
+	t.Helper()
+
+	previous := titleDenyList.Load()
+	t.Cleanup(func() { titleDenyList.Store(previous) })
+
+	SetTitleDenyList(entries)
+}
+
+func TestTitleDenied(t *testing.T) {
+	setTitleDenyList(t, []string{"ass", "Bad  Words", "", "  ", "x-rated"})
+
+	tests := []struct {
+		title string
+		want  bool
+	}{
+		{"Kick-Ass", true},
+		{"The Glass Castle", false},
+		{"Assassins", false},
+		{"ASS", true},
+		{"Some bad words here", true},
+		{"Some BAD, words here", true},
+		{"Bad guys and words", false},
+		{"Badwords", false},
+		{"An X-Rated Movie", true},
+		{"X Rated", true},
+		{"Casablanca", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			if got := titleDenied(tt.title); got != tt.want {
+				t.Errorf("got %t; want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTitleDeniedEmptyList(t *testing.T) {
+	setTitleDenyList(t, nil)
+
+	if titleDenied("Kick-Ass") {
+		t.Error("got a title denied by an empty list")
+	}
+}
+
+func TestLoadTitleDenyList(t *testing.T) {
+	setTitleDenyList(t, nil)
+
+	path := filepath.Join(t.TempDir(), "deny.txt")
+	err := os.WriteFile(path, []byte("# Words we won't allow\n\nass\n  bad words  \n#commented\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = LoadTitleDenyList(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]bool{
+		"Kick-Ass":            true,
+		"Bad words":           true,
+		"commented":           false,
+		"Words we won't":      false,
+		"# Words we won't":    false,
+		"The Glass Castle":    false,
+		"A title with no ass": true,
+	}
+	for title, want := range tests {
+		if got := titleDenied(title); got != want {
+			t.Errorf("got %t for %q; want %t", got, title, want)
+		}
+	}
+
+	// A file that can't be read keeps the list loaded before.
+	err = LoadTitleDenyList(filepath.Join(t.TempDir(), "missing.txt"))
+	if err == nil {
+		t.Fatal("got no error loading a missing file")
+	}
+	if !titleDenied("Kick-Ass") {
+		t.Error("got the previous list dropped after a failed reload")
+	}
+
+	err = LoadTitleDenyList(t.TempDir())
+	if err == nil {
+		t.Fatal("got no error loading a directory")
+	}
+	if !titleDenied("Kick-Ass") {
+		t.Error("got the previous list dropped after a failed reload")
+	}
+
+	// An empty path clears the list.
+	err = LoadTitleDenyList("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if titleDenied("Kick-Ass") {
+		t.Error("got a title denied after clearing the list")
+	}
+}
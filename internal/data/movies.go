@@ -1,13 +1,48 @@
 package data
 
 import (
+	"encoding/json"
+	"fmt"
 	"greenlight/internal/validator"
 	"time"
 )
 
+// ValidateGenreLimits checks the genre limits to be passed to ValidateMovie(). A
+// movie must always have at least one genre, which the database also enforces.
+func ValidateGenreLimits(min, max int) error {
+	if min < 1 || max < min {
+		return fmt.Errorf("invalid genre limits %d-%d: need 1 <= min <= max", min, max)
+	}
+	return nil
+}
+
+// The supported names for SetEmptyArrayBehavior().
+const (
+	EmptyArrayEmpty = "empty"
+	EmptyArrayNull  = "null"
+	EmptyArrayOmit  = "omit"
+)
+
+// emptyArrayBehavior has to be package state because Movie.MarshalJSON() is called
+// by encoding/json, which gives it no way to receive options. Change it only through
+// SetEmptyArrayBehavior(), before any movies are encoded.
+var emptyArrayBehavior = EmptyArrayOmit
+
+// SetEmptyArrayBehavior selects how an empty Genres slice is encoded: as [] (empty),
+// as null (null), or by leaving the field out entirely (omit).
+func SetEmptyArrayBehavior(name string) error {
+	switch name {
+	case EmptyArrayEmpty, EmptyArrayNull, EmptyArrayOmit:
+		emptyArrayBehavior = name
+		return nil
+	default:
+		return fmt.Errorf("unsupported empty array behavior %q", name)
+	}
+}
+
 type Movie struct {
 	ID        int64     `json:"id"`
-	CreatedAt time.Time `json:"created_at,omitempty"`
+	CreatedAt Timestamp `json:"created_at,omitempty"`
 	Title     string    `json:"title"`
 	Year      int32     `json:"year,omitempty"`
 	Runtime   Runtime   `json:"runtime,omitempty"`
@@ -15,12 +50,55 @@ type Movie struct {
 	Version   int32     `json:"version"`
 }
 
-func ValidateMovie(v *validator.Validator, movie *Movie) {
+// Implement a MarshalJSON() method on Movie so that empty genres are encoded
+// according to the configured empty array behavior. Every other field is encoded
+// as usual.
+func (m Movie) MarshalJSON() ([]byte, error) {
+	var genres any = m.Genres
+	if len(m.Genres) == 0 {
+		switch emptyArrayBehavior {
+		case EmptyArrayEmpty:
+			genres = []string{}
+		case EmptyArrayNull:
+			genres = json.RawMessage("null")
+		default:
+			genres = nil
+		}
+	}
+
+	// The aux struct repeats Movie's fields in the same order, so that the keys are
+	// encoded in that order too, with Genres as any. Fields added to Movie must be
+	// added here as well.
+	aux := struct {
+		ID        int64     `json:"id"`
+		CreatedAt Timestamp `json:"created_at,omitempty"`
+		Title     string    `json:"title"`
+		Year      int32     `json:"year,omitempty"`
+		Runtime   Runtime   `json:"runtime,omitempty"`
+		Genres    any       `json:"genres,omitempty"`
+		Version   int32     `json:"version"`
+	}{
+		ID:        m.ID,
+		CreatedAt: m.CreatedAt,
+		Title:     m.Title,
+		Year:      m.Year,
+		Runtime:   m.Runtime,
+		Genres:    genres,
+		Version:   m.Version,
+	}
+
+	return json.Marshal(aux)
+}
+
+// ValidateMovie checks a movie against our rules, including that it has between
+// minGenres and maxGenres genres.
+func ValidateMovie(v *validator.Validator, movie *Movie, minGenres, maxGenres int) {
 	// Use the Check() method to execute our validation checks. This will add the
 	// provided key and error message to the errors map if the check does not evaluate
 	// to true.
 	v.Check(movie.Title != "", "title", "must be provided")
 	v.Check(len(movie.Title) <= 500, "title", "cannot be more than 500 bytes long")
+	v.Check(!titleDenied(movie.Title), "title", "must not contain banned words")
 
 	v.Check(movie.Year != 0, "year", "must be provided")
 	v.Check(movie.Year >= 1888, "year", "must be greater than 1888")
@@ -29,9 +107,11 @@ func ValidateMovie(v *validator.Validator, movie *Movie) {
 	v.Check(movie.Runtime != 0, "runtime", "must be provided")
 	v.Check(movie.Runtime > 0, "runtime", "must be a positive integer")
 
-	v.Check(movie.Genres != nil, "genres", "must be provided")
-	v.Check(len(movie.Genres) >= 1, "genres", "must contain at least 1 genre")
-	v.Check(len(movie.Genres) <= 5, "genres", "cannot contain more than 5 genres")
+	// A missing genres field, null and [] all mean "no genres", so they are all
+	// reported the same way.
+	v.Check(len(movie.Genres) != 0, "genres", "must be provided")
+	v.Check(len(movie.Genres) >= minGenres, "genres", "must contain at least %d genres", minGenres)
+	v.Check(len(movie.Genres) <= maxGenres, "genres", "cannot contain more than %d genres", maxGenres)
 
 	v.Check(validator.Unique(movie.Genres), "genres", "cannot not contain duplicate values")
 }
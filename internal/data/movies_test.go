@@ -0,0 +1,138 @@
+package data
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"greenlight/internal/validator"
+)
+
+func TestMovieMarshalJSONEmptyGenres(t *testing.T) {
+	tests := []struct {
+		behavior string
+		want     string
+	}{
+		{EmptyArrayEmpty, `{"id":1,"created_at":"0001-01-01T00:00:00Z","title":"Moana","genres":[],"version":1}`},
+		{EmptyArrayNull, `{"id":1,"created_at":"0001-01-01T00:00:00Z","title":"Moana","genres":null,"version":1}`},
+		{EmptyArrayOmit, `{"id":1,"created_at":"0001-01-01T00:00:00Z","title":"Moana","version":1}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.behavior, func(t *testing.T) {
+			previous := emptyArrayBehavior
+			t.Cleanup(func() { emptyArrayBehavior = previous })
+
+			err := SetEmptyArrayBehavior(tt.behavior)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// A nil and an empty slice must be encoded the same way.
+			for _, genres := range [][]string{nil, {}} {
+				js, err := json.Marshal(Movie{ID: 1, Title: "Moana", Version: 1, Genres: genres})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(js) != tt.want {
+					t.Errorf("got %s; want %s", js, tt.want)
+				}
+			}
+
+			// Non-empty genres are unaffected by the setting.
+			js, err := json.Marshal(Movie{ID: 1, Title: "Moana", Version: 1, Genres: []string{"animation"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := `{"id":1,"created_at":"0001-01-01T00:00:00Z","title":"Moana","genres":["animation"],"version":1}`; string(js) != want {
+				t.Errorf("got %s; want %s", js, want)
+			}
+		})
+	}
+}
+
+func TestMovieMarshalJSONKeyOrder(t *testing.T) {
+	movie := Movie{ID: 1, Title: "Moana", Year: 2016, Runtime: 107, Genres: []string{"animation"}, Version: 1}
+
+	js, err := json.Marshal(movie)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The keys must stay in the order of the Movie struct fields.
+	keys := []string{`"id"`, `"created_at"`, `"title"`, `"year"`, `"runtime"`, `"genres"`, `"version"`}
+	last := -1
+	for _, key := range keys {
+		i := strings.Index(string(js), key)
+		if i < last {
+			t.Fatalf("got %s; want the keys in the order %v", js, keys)
+		}
+		last = i
+	}
+}
+
+func TestValidateMovieGenreLimits(t *testing.T) {
+	tests := []struct {
+		genres   []string
+		min, max int
+		want     string
+	}{
+		{[]string{"drama"}, 1, 5, ""},
+		{[]string{"drama"}, 2, 5, "must contain at least 2 genres"},
+		{[]string{"drama", "war", "romance"}, 1, 2, "cannot contain more than 2 genres"},
+		{[]string{"drama", "war"}, 2, 2, ""},
+	}
+
+	for _, tt := range tests {
+		movie := Movie{Title: "Casablanca", Year: 1942, Runtime: 102, Genres: tt.genres}
+
+		v := validator.New()
+		ValidateMovie(v, &movie, tt.min, tt.max)
+
+		if got := v.Errors["genres"]; got != tt.want {
+			t.Errorf("got %q for %d genres with limits %d-%d; want %q", got, len(tt.genres), tt.min, tt.max, tt.want)
+		}
+	}
+}
+
+func TestValidateGenreLimits(t *testing.T) {
+	for _, limits := range [][2]int{{0, 5}, {3, 2}, {-1, -1}} {
+		if err := ValidateGenreLimits(limits[0], limits[1]); err == nil {
+			t.Errorf("got no error for limits %v", limits)
+		}
+	}
+	if err := ValidateGenreLimits(1, 1); err != nil {
+		t.Errorf("got error %s for limits 1-1", err)
+	}
+}
+
+func TestSetEmptyArrayBehaviorUnsupported(t *testing.T) {
+	if err := SetEmptyArrayBehavior("skip"); err == nil {
+		t.Error("got no error for an unsupported behavior")
+	}
+}
+
+func TestValidateMovieNoGenres(t *testing.T) {
+	inputs := map[string]string{
+		"Missing": `{"title":"Moana","year":2016,"runtime":"107 mins"}`,
+		"Null":    `{"title":"Moana","year":2016,"runtime":"107 mins","genres":null}`,
+		"Empty":   `{"title":"Moana","year":2016,"runtime":"107 mins","genres":[]}`,
+	}
+
+	for name, input := range inputs {
+		t.Run(name, func(t *testing.T) {
+			var movie Movie
+			err := json.NewDecoder(strings.NewReader(input)).Decode(&movie)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			v := validator.New()
+			ValidateMovie(v, &movie, 1, 5)
+
+			if len(v.Errors) != 1 || v.Errors["genres"] != "must be provided" {
+				t.Errorf("got errors %v; want only genres: must be provided", v.Errors)
+			}
+		})
+	}
+}
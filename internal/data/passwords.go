@@ -0,0 +1,72 @@
+package data
+
+import (
+	_ "embed"
+	"strings"
+	"unicode"
+
+	"greenlight/internal/validator"
+)
+
+// commonPasswordsList is a newline-separated list of passwords that are too common
+// to be accepted, however they are capitalized.
+//
+//go:embed common_passwords.txt
+var commonPasswordsList string
+
+var commonPasswords = func() map[string]bool {
+	set := make(map[string]bool)
+	for _, password := range strings.Fields(commonPasswordsList) {
+		set[strings.ToLower(password)] = true
+	}
+	return set
+}()
+
+// ValidatePasswordPlaintext checks a candidate password against our password
+// rules. Rule failures are keyed on the rule name rather than a field name, so that
+// callers can report every rule that failed.
+func ValidatePasswordPlaintext(v *validator.Validator, password string) {
+	v.Check(password != "", "length", "must be provided")
+	v.Check(len(password) >= 8, "length", "must be at least 8 bytes long")
+	v.Check(len(password) <= 72, "length", "must not be more than %d bytes long", 72)
+
+	v.Check(!commonPasswords[strings.ToLower(password)], "common", "must not be a commonly used password")
+}
+
+// PasswordStrength scores a password from 0 (unacceptable) to 4 (strong). A password
+// that fails ValidatePasswordPlaintext() always scores 0. Otherwise it scores 1, plus
+// a point each for being at least 12 and 16 bytes long, and a point for mixing at
+// least three kinds of character (lower case, upper case, digits and symbols).
+func PasswordStrength(password string) int {
+	v := validator.New()
+	if ValidatePasswordPlaintext(v, password); !v.Valid() {
+		return 0
+	}
+
+	score := 1
+	if len(password) >= 12 {
+		score++
+	}
+	if len(password) >= 16 {
+		score++
+	}
+
+	var lower, upper, digit, symbol int
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			symbol = 1
+		}
+	}
+	if lower+upper+digit+symbol >= 3 {
+		score++
+	}
+
+	return score
+}
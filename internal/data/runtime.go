@@ -3,12 +3,18 @@ package data
 import (
 	"errors"
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
+	"time"
 )
 
 var ErrInvalidRuntimeFormat = errors.New("invalid runtime format")
 
+// ErrRuntimeOutOfRange is returned when a runtime is well-formed but too long to be
+// stored. Callers should report it as a validation error on the runtime field.
+var ErrRuntimeOutOfRange = errors.New("runtime out of range")
+
 // If we want to customize how something is encoded, all we need to do is implement a MarshalJSON()
 // method on it which returns a custom JSON representation of itself in a []byte slice.
 
@@ -30,32 +36,111 @@ func (r Runtime) MarshalJSON() ([]byte, error) {
 	return []byte(quotedJSONValue), nil
 }
 
+// UnmarshalJSON accepts a runtime in any of these forms, always stored as whole
+// minutes:
+//
+//   - "<runtime> mins", e.g. "107 mins" (the format we produce on output)
+//   - a Go duration string, e.g. "1h47m" or "6420s"
+//   - "HH:MM", e.g. "1:47"
+//
+// Go durations that aren't a whole number of minutes are rounded to the nearest
+// minute, with halves rounded up, so "1h47m30s" becomes 108 minutes. A runtime that
+// rounds to zero or is negative decodes successfully and is then rejected by
+// ValidateMovie(), however negative it is. A well-formed runtime of more than
+// math.MaxInt32 minutes is an ErrRuntimeOutOfRange. Go durations can't exceed about
+// 292 years, so a longer one is an ErrInvalidRuntimeFormat.
 func (r *Runtime) UnmarshalJSON(jsonValue []byte) error {
-	// We expect that the incoming JSON value will be a string in the format
-	// "<runtime> mins", and the first thing we need to do is remove the surrounding
-	// double-quotes from this string. If we can't unquote it, then we return the
-	// ErrInvalidRuntimeFormat error.
+	// We expect that the incoming JSON value will be a string, and the first thing we
+	// need to do is remove the surrounding double-quotes from this string. If we
+	// can't unquote it, then we return the ErrInvalidRuntimeFormat error.
 	upquotedJSONValue, err := strconv.Unquote(string(jsonValue))
 	if err != nil {
 		return ErrInvalidRuntimeFormat
 	}
 
+	var minutes int64
+
+	switch {
+	case strings.HasSuffix(upquotedJSONValue, " mins"):
+		minutes, err = parseMins(upquotedJSONValue)
+	case strings.Contains(upquotedJSONValue, ":"):
+		minutes, err = parseHoursMinutes(upquotedJSONValue)
+	default:
+		minutes, err = parseDurationMinutes(upquotedJSONValue)
+	}
+	if err != nil {
+		return err
+	}
+
+	// The sign of a very negative runtime is all that ValidateMovie() needs.
+	if minutes < math.MinInt32 {
+		minutes = math.MinInt32
+	}
+	if minutes > math.MaxInt32 {
+		return ErrRuntimeOutOfRange
+	}
+
+	// Convert the minutes to a Runtime type and assign this to the receiver.
+	*r = Runtime(minutes)
+	return nil
+}
+
+// parseMins parses the "<runtime> mins" format.
+func parseMins(value string) (int64, error) {
 	// Split the string to isolate the part containing the number.
-	parts := strings.Split(upquotedJSONValue, " ")
+	parts := strings.Split(value, " ")
 
 	// Sanity check the parts of the string to make sure it was in the expected format.
 	if len(parts) != 2 || parts[1] != "mins" {
-		return ErrInvalidRuntimeFormat
+		return 0, ErrInvalidRuntimeFormat
 	}
 
-	// Otherwise, parse the string containing the number into an int32.
-	i, err := strconv.ParseInt(parts[0], 10, 32)
+	// Otherwise, parse the string containing the number. Range checking is left to
+	// the caller, except for numbers too big to parse at all.
+	i, err := strconv.ParseInt(parts[0], 10, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		if i < 0 {
+			return math.MinInt32, nil
+		}
+		return 0, ErrRuntimeOutOfRange
+	}
 	if err != nil {
-		return ErrInvalidRuntimeFormat
+		return 0, ErrInvalidRuntimeFormat
 	}
+	return i, nil
+}
 
-	// Convert the int32 to a Runtime type and assign this to the receiver.
-	*r = Runtime(i)
-	return nil
+// parseHoursMinutes parses the "HH:MM" format. The minutes must be between 00 and
+// 59, but the hours may have any number of digits.
+func parseHoursMinutes(value string) (int64, error) {
+	hh, mm, _ := strings.Cut(value, ":")
+	if len(mm) != 2 {
+		return 0, ErrInvalidRuntimeFormat
+	}
+
+	hours, err := strconv.ParseUint(hh, 10, 32)
+	if errors.Is(err, strconv.ErrRange) {
+		return 0, ErrRuntimeOutOfRange
+	}
+	if err != nil {
+		return 0, ErrInvalidRuntimeFormat
+	}
+
+	minutes, err := strconv.ParseUint(mm, 10, 8)
+	if err != nil || minutes > 59 {
+		return 0, ErrInvalidRuntimeFormat
+	}
+
+	return int64(hours)*60 + int64(minutes), nil
+}
+
+// parseDurationMinutes parses a Go duration string and rounds it to the nearest
+// minute.
+func parseDurationMinutes(value string) (int64, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, ErrInvalidRuntimeFormat
+	}
 
+	return int64(d.Round(time.Minute) / time.Minute), nil
 }
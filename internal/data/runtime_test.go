@@ -0,0 +1,68 @@
+package data
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestRuntimeUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    Runtime
+		wantErr error
+	}{
+		{"Mins", `"107 mins"`, 107, nil},
+		{"Zero mins", `"0 mins"`, 0, nil},
+		{"Negative mins", `"-5 mins"`, -5, nil},
+		{"Duration", `"1h47m"`, 107, nil},
+		{"Duration in seconds", `"6420s"`, 107, nil},
+		{"Duration rounded down", `"1h47m29s"`, 107, nil},
+		{"Duration half rounded up", `"1h47m30s"`, 108, nil},
+		{"Sub-minute duration", `"20s"`, 0, nil},
+		{"HH:MM", `"1:47"`, 107, nil},
+		{"HH:MM with leading zero", `"01:07"`, 67, nil},
+		{"HH:MM many hours", `"100:00"`, 6000, nil},
+		{"Largest mins", `"2147483647 mins"`, math.MaxInt32, nil},
+		{"Very negative mins", `"-99999999999 mins"`, math.MinInt32, nil},
+
+		{"Mins out of range", `"2147483648 mins"`, 0, ErrRuntimeOutOfRange},
+		{"Mins overflowing int64", `"99999999999999999999 mins"`, 0, ErrRuntimeOutOfRange},
+		{"HH:MM out of range", `"40000000:00"`, 0, ErrRuntimeOutOfRange},
+		{"HH:MM overflowing", `"99999999999:00"`, 0, ErrRuntimeOutOfRange},
+
+		{"Not a string", `107`, 0, ErrInvalidRuntimeFormat},
+		{"Bad unit", `"107 minutes"`, 0, ErrInvalidRuntimeFormat},
+		{"Mins not a number", `"abc mins"`, 0, ErrInvalidRuntimeFormat},
+		{"Minutes over 59", `"1:60"`, 0, ErrInvalidRuntimeFormat},
+		{"One-digit minutes", `"1:7"`, 0, ErrInvalidRuntimeFormat},
+		{"Negative HH:MM", `"-1:07"`, 0, ErrInvalidRuntimeFormat},
+		{"Bad duration", `"1 hour"`, 0, ErrInvalidRuntimeFormat},
+		{"Duration overflowing", `"9999999h"`, 0, ErrInvalidRuntimeFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var r Runtime
+			err := json.Unmarshal([]byte(tt.input), &r)
+
+			if err != tt.wantErr {
+				t.Fatalf("got error %v; want %v", err, tt.wantErr)
+			}
+			if r != tt.want {
+				t.Errorf("got %d; want %d", r, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuntimeMarshalJSON(t *testing.T) {
+	js, err := json.Marshal(Runtime(107))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(js) != `"107 mins"` {
+		t.Errorf("got %s; want %s", js, `"107 mins"`)
+	}
+}
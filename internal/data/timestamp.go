@@ -0,0 +1,79 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+var ErrInvalidTimestampFormat = errors.New("invalid timestamp format")
+
+// The supported names for SetTimeFormat().
+const (
+	TimeFormatRFC3339   = "rfc3339"
+	TimeFormatRFC3339Ms = "rfc3339ms"
+	TimeFormatUnix      = "unix"
+)
+
+// timeFormat is shared by every Timestamp, since the JSON marshaler and unmarshaler
+// methods have nowhere else to find it. SetTimeFormat() is meant to be called once,
+// during startup.
+var timeFormat = TimeFormatRFC3339
+
+// SetTimeFormat selects the JSON representation used for all Timestamp values:
+// RFC 3339 strings with second or millisecond precision, or Unix epoch seconds.
+func SetTimeFormat(name string) error {
+	switch name {
+	case TimeFormatRFC3339, TimeFormatRFC3339Ms, TimeFormatUnix:
+		timeFormat = name
+		return nil
+	default:
+		return fmt.Errorf("unsupported time format %q", name)
+	}
+}
+
+// Declare a custom Timestamp type so that the JSON encoding of times such as a
+// movie's created_at can be chosen by configuration.
+type Timestamp time.Time
+
+// Implement a MarshalJSON() method so that Timestamp satisfies the json.Marshaler
+// interface and is encoded according to the configured time format.
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	tm := time.Time(t)
+
+	switch timeFormat {
+	case TimeFormatUnix:
+		return []byte(strconv.FormatInt(tm.Unix(), 10)), nil
+	case TimeFormatRFC3339Ms:
+		return []byte(strconv.Quote(tm.Format("2006-01-02T15:04:05.000Z07:00"))), nil
+	default:
+		return []byte(strconv.Quote(tm.Format(time.RFC3339))), nil
+	}
+}
+
+// UnmarshalJSON accepts input in the configured time format: a JSON number for Unix
+// epoch seconds, or otherwise an RFC 3339 string (with optional fractional seconds).
+func (t *Timestamp) UnmarshalJSON(jsonValue []byte) error {
+	if timeFormat == TimeFormatUnix {
+		seconds, err := strconv.ParseInt(string(jsonValue), 10, 64)
+		if err != nil {
+			return ErrInvalidTimestampFormat
+		}
+		*t = Timestamp(time.Unix(seconds, 0).UTC())
+		return nil
+	}
+
+	unquotedJSONValue, err := strconv.Unquote(string(jsonValue))
+	if err != nil {
+		return ErrInvalidTimestampFormat
+	}
+
+	tm, err := time.Parse(time.RFC3339, unquotedJSONValue)
+	if err != nil {
+		return ErrInvalidTimestampFormat
+	}
+
+	*t = Timestamp(tm)
+	return nil
+}
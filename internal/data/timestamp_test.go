@@ -0,0 +1,92 @@
+package data
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// setTimeFormat switches the time format for the rest of a test.
+func setTimeFormat(t *testing.T, name string) {
+	t.Helper()
+
+	previous := timeFormat
+	t.Cleanup(func() { timeFormat = previous })
+
+	err := SetTimeFormat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTimestampMarshalJSON(t *testing.T) {
+	ts := Timestamp(time.Date(2023, time.March, 4, 5, 6, 7, 890_000_000, time.UTC))
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{TimeFormatRFC3339, `"2023-03-04T05:06:07Z"`},
+		{TimeFormatRFC3339Ms, `"2023-03-04T05:06:07.890Z"`},
+		{TimeFormatUnix, `1677906367`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			setTimeFormat(t, tt.format)
+
+			js, err := json.Marshal(ts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(js) != tt.want {
+				t.Errorf("got %s; want %s", js, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimestampUnmarshalJSON(t *testing.T) {
+	want := time.Date(2023, time.March, 4, 5, 6, 7, 0, time.UTC)
+
+	tests := []struct {
+		format  string
+		input   string
+		wantErr bool
+	}{
+		{TimeFormatRFC3339, `"2023-03-04T05:06:07Z"`, false},
+		{TimeFormatRFC3339, `"2023-03-04T07:06:07+02:00"`, false},
+		{TimeFormatRFC3339, `1677906367`, true},
+		{TimeFormatRFC3339Ms, `"2023-03-04T05:06:07.000Z"`, false},
+		{TimeFormatUnix, `1677906367`, false},
+		{TimeFormatUnix, `"2023-03-04T05:06:07Z"`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format+" "+tt.input, func(t *testing.T) {
+			setTimeFormat(t, tt.format)
+
+			var ts Timestamp
+			err := json.Unmarshal([]byte(tt.input), &ts)
+
+			if tt.wantErr {
+				if err != ErrInvalidTimestampFormat {
+					t.Errorf("got error %v; want %v", err, ErrInvalidTimestampFormat)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !time.Time(ts).Equal(want) {
+				t.Errorf("got %s; want %s", time.Time(ts), want)
+			}
+		})
+	}
+}
+
+func TestSetTimeFormatUnsupported(t *testing.T) {
+	if err := SetTimeFormat("iso8601"); err == nil {
+		t.Error("got no error for an unsupported format")
+	}
+}
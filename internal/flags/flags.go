@@ -0,0 +1,63 @@
+package flags
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// envPrefix is the prefix for environment variables that toggle features, so that
+// FEATURE_NDJSON=true enables the "ndjson" feature.
+const envPrefix = "FEATURE_"
+
+// Flags is a registry of named feature flags. It is populated once at startup and
+// is safe for concurrent reads after that.
+type Flags struct {
+	enabled map[string]bool
+}
+
+// New returns an empty registry in which every feature is disabled.
+func New() *Flags {
+	return &Flags{enabled: make(map[string]bool)}
+}
+
+// Set enables or disables a feature. Feature names are case-insensitive.
+func (f *Flags) Set(name string, enabled bool) {
+	f.enabled[strings.ToLower(name)] = enabled
+}
+
+// Enabled returns true if the named feature has been enabled.
+func (f *Flags) Enabled(name string) bool {
+	return f.enabled[strings.ToLower(name)]
+}
+
+// Active returns the names of all enabled features in alphabetical order.
+func (f *Flags) Active() []string {
+	active := []string{}
+	for name, enabled := range f.enabled {
+		if enabled {
+			active = append(active, name)
+		}
+	}
+	sort.Strings(active)
+	return active
+}
+
+// LoadEnv sets features from FEATURE_<NAME>=<bool> entries in environ, which is in
+// the same "key=value" format returned by os.Environ().
+func (f *Flags) LoadEnv(environ []string) error {
+	for _, kv := range environ {
+		key, value, found := strings.Cut(kv, "=")
+		if !found || !strings.HasPrefix(key, envPrefix) {
+			continue
+		}
+
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid value %q for %s: must be a boolean", value, key)
+		}
+		f.Set(strings.TrimPrefix(key, envPrefix), enabled)
+	}
+	return nil
+}
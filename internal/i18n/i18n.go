@@ -0,0 +1,102 @@
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"greenlight/internal/validator"
+
+	"golang.org/x/text/language"
+)
+
+// Translations live in locales/<tag>.json, each mapping an English message template
+// (the source text used throughout the code) to its translation. Templates may
+// contain %d and %s verbs, in which case the translation must use the same verbs,
+// optionally reordered with explicit argument indexes such as %[2]d. Templates are
+// looked up before they are formatted, so the arguments never affect the lookup.
+//
+//go:embed locales/*.json
+var localeFS embed.FS
+
+// Translator localizes messages into the best language for a request, falling back
+// to English (the source language) for unsupported languages and for messages that
+// have no translation.
+type Translator struct {
+	tags     []language.Tag
+	matcher  language.Matcher
+	catalogs map[language.Tag]map[string]string
+}
+
+// New loads all embedded translation files.
+func New() (*Translator, error) {
+	t := &Translator{
+		tags:     []language.Tag{language.English},
+		catalogs: make(map[language.Tag]map[string]string),
+	}
+
+	files, err := localeFS.ReadDir("locales")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		name := file.Name()
+		tag, err := language.Parse(strings.TrimSuffix(name, path.Ext(name)))
+		if err != nil {
+			return nil, fmt.Errorf("locale file %s: %w", name, err)
+		}
+
+		js, err := localeFS.ReadFile("locales/" + name)
+		if err != nil {
+			return nil, err
+		}
+
+		var messages map[string]string
+		err = json.Unmarshal(js, &messages)
+		if err != nil {
+			return nil, fmt.Errorf("locale file %s: %w", name, err)
+		}
+
+		t.tags = append(t.tags, tag)
+		t.catalogs[tag] = messages
+	}
+
+	t.matcher = language.NewMatcher(t.tags)
+	return t, nil
+}
+
+// Match returns the supported language that best fits an Accept-Language header.
+func (t *Translator) Match(acceptLanguage string) language.Tag {
+	prefs, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(prefs) == 0 {
+		return language.English
+	}
+
+	_, index, confidence := t.matcher.Match(prefs...)
+	if confidence == language.No {
+		return language.English
+	}
+	return t.tags[index]
+}
+
+// Translate returns the message template in the given language, or unchanged if
+// there is no translation for it, formatted with args.
+func (t *Translator) Translate(tag language.Tag, template string, args ...any) string {
+	if translation, ok := t.catalogs[tag][template]; ok {
+		template = translation
+	}
+	return validator.Message{Template: template, Args: args}.String()
+}
+
+// TranslateAll returns a map of validation errors with every message translated and
+// formatted. The keys are left untouched so that they stay stable across languages.
+func (t *Translator) TranslateAll(tag language.Tag, messages map[string]validator.Message) map[string]string {
+	translated := make(map[string]string, len(messages))
+	for key, message := range messages {
+		translated[key] = t.Translate(tag, message.Template, message.Args...)
+	}
+	return translated
+}
@@ -0,0 +1,62 @@
+package i18n
+
+import (
+	"math"
+	"testing"
+
+	"greenlight/internal/validator"
+
+	"golang.org/x/text/language"
+)
+
+func TestTranslateAll(t *testing.T) {
+	translator, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages := map[string]validator.Message{
+		"title":    {Template: "must be provided"},
+		"genres":   {Template: "must contain at least %d genres", Args: []any{2}},
+		"runtime":  {Template: "must not be more than %d minutes", Args: []any{math.MaxInt32}},
+		"password": {Template: "must not be more than %d bytes long", Args: []any{72}},
+		"other":    {Template: "has no translation (%d)", Args: []any{7}},
+	}
+
+	tests := []struct {
+		tag  language.Tag
+		want map[string]string
+	}{
+		{
+			tag: language.English,
+			want: map[string]string{
+				"title":    "must be provided",
+				"genres":   "must contain at least 2 genres",
+				"runtime":  "must not be more than 2147483647 minutes",
+				"password": "must not be more than 72 bytes long",
+				"other":    "has no translation (7)",
+			},
+		},
+		{
+			tag: language.German,
+			want: map[string]string{
+				"title":    "muss angegeben werden",
+				"genres":   "muss mindestens 2 Genres enthalten",
+				"runtime":  "darf nicht länger als 2147483647 Minuten sein",
+				"password": "darf nicht länger als 72 Bytes sein",
+				"other":    "has no translation (7)",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tag.String(), func(t *testing.T) {
+			got := translator.TranslateAll(tt.tag, messages)
+			for key, want := range tt.want {
+				if got[key] != want {
+					t.Errorf("got %q for %q; want %q", got[key], key, want)
+				}
+			}
+		})
+	}
+}
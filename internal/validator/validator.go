@@ -1,19 +1,41 @@
 package validator
 
-import "regexp"
+import (
+	"fmt"
+	"regexp"
+)
 
 var (
 	EmailRX = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+\\/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
 )
 
-// Define a new validator type which contains a map of validation errors
+// A Message is a validation error message before formatting: an English template,
+// which may contain fmt verbs, and the arguments for them. Keeping the two apart lets
+// the template be translated before the arguments are filled in.
+type Message struct {
+	Template string
+	Args     []any
+}
+
+// String returns the message formatted in English.
+func (m Message) String() string {
+	if len(m.Args) == 0 {
+		return m.Template
+	}
+	return fmt.Sprintf(m.Template, m.Args...)
+}
+
+// Define a new validator type which contains a map of validation errors. Errors holds
+// the formatted English messages and Messages the same messages unformatted, for
+// translation.
 type Validator struct {
-	Errors map[string]string
+	Errors   map[string]string
+	Messages map[string]Message
 }
 
-// New is a helper which creates a new Validator instance with an empty errors map.
+// New is a helper which creates a new Validator instance with empty errors maps.
 func New() *Validator {
-	return &Validator{Errors: make(map[string]string)}
+	return &Validator{Errors: make(map[string]string), Messages: make(map[string]Message)}
 }
 
 // Valid returns true if the errors map doesn't contain any entries
@@ -21,18 +43,20 @@ func (v *Validator) Valid() bool {
 	return len(v.Errors) == 0
 }
 
-// AddError adds an error message to the map
+// AddError adds an error message, formatted with any args, to the map
 // (so long as no entry already exists for the given key).
-func (v *Validator) AddError(key, message string) {
+func (v *Validator) AddError(key, message string, args ...any) {
 	if _, exists := v.Errors[key]; !exists {
-		v.Errors[key] = message
+		m := Message{Template: message, Args: args}
+		v.Errors[key] = m.String()
+		v.Messages[key] = m
 	}
 }
 
 // Checks adds an error message to the map only if a validation check is not 'ok'
-func (v *Validator) Check(ok bool, key, message string) {
+func (v *Validator) Check(ok bool, key, message string, args ...any) {
 	if !ok {
-		v.AddError(key, message)
+		v.AddError(key, message, args...)
 	}
 }
 
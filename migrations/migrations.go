@@ -0,0 +1,41 @@
+// Package migrations embeds the SQL migration files so that the application knows
+// which schema version it was built against.
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// ExpectedVersion returns the highest migration version embedded in the binary,
+// taken from the numeric prefix of the file names (e.g. 000002_add_... is 2).
+func ExpectedVersion() (int64, error) {
+	entries, err := fs.ReadDir(files, ".")
+	if err != nil {
+		return 0, err
+	}
+
+	var latest int64
+	for _, entry := range entries {
+		prefix, _, found := strings.Cut(entry.Name(), "_")
+		if !found {
+			continue
+		}
+
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("migration %s: invalid version prefix", entry.Name())
+		}
+		if version > latest {
+			latest = version
+		}
+	}
+
+	return latest, nil
+}